@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"time"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/role"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Search represents an account search. The query is matched against account emails and ids. An empty
+// query matches every account.
+type Search struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// Validate validates account search data.
+func (s Search) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Query, validation.RuneLength(0, 254)),
+		validation.Field(&s.Limit, validation.Min(1), validation.Max(100)),
+		validation.Field(&s.Offset, validation.Min(0)),
+	)
+}
+
+// Summary represents an account in search results.
+type Summary struct {
+	ID          int        `json:"id" db:"id"`
+	Email       *string    `json:"email" db:"email"`
+	DisplayName *string    `json:"display_name" db:"display_name"`
+	VerifiedAt  *time.Time `json:"verified_at" db:"verified_at"`
+	DeletedAt   *time.Time `json:"deleted_at" db:"deleted_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Page represents a page of account search results along with the total number of matches.
+type Page struct {
+	Accounts []Summary `json:"accounts"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+}
+
+// Account represents the details of an account that are shown to admins.
+type Account struct {
+	ID                  int        `json:"id" db:"id"`
+	Email               *string    `json:"email" db:"email"`
+	DisplayName         *string    `json:"display_name" db:"display_name"`
+	Guest               bool       `json:"guest" db:"guest"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled" db:"two_factor_enabled"`
+	VerifiedAt          *time.Time `json:"verified_at" db:"verified_at"`
+	LastLoginAt         *time.Time `json:"last_login_at" db:"last_login_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at" db:"deletion_scheduled_at"`
+	DeletedAt           *time.Time `json:"deleted_at" db:"deleted_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Details represents an account along with its roles and bans.
+type Details struct {
+	Account Account    `json:"account"`
+	Roles   role.Roles `json:"roles"`
+	Bans    []ban.Ban  `json:"bans"`
+}
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrOutranked is used when an admin attempts an action on an account that holds more privileged
+// roles than the admin.
+var ErrOutranked = errors.New("account holds more privileged roles")
+
+// ErrGuestAccount is used when attempting an action that requires an email address on a guest
+// account.
+var ErrGuestAccount = errors.New("guest account")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Search(s Search) ([]Summary, int, error)
+	Get(id int) (Account, error)
+	Verify(id int) error
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Search retrieves a page of accounts whose email contains the query or whose id equals the query,
+// along with the total number of matching accounts.
+func (r *accountRepository) Search(s Search) ([]Summary, int, error) {
+	const (
+		qFilter = ` FROM accounts WHERE $1 = '' OR email ILIKE '%' || $2 || '%' ESCAPE '\' OR id = $3`
+		qCount  = `SELECT count(*)` + qFilter
+		qSearch = `SELECT id, email, display_name, verified_at, deleted_at, created_at` + qFilter + ` ORDER BY id LIMIT $4 OFFSET $5`
+	)
+
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s.Query)
+
+	var id sql.NullInt64
+	if n, err := strconv.Atoi(s.Query); err == nil {
+		id = sql.NullInt64{Int64: int64(n), Valid: true}
+	}
+
+	var total int
+	if err := r.db.Get(&total, qCount, s.Query, pattern, id); err != nil {
+		return nil, 0, err
+	}
+
+	accounts := []Summary{}
+	if err := r.db.Select(&accounts, qSearch, s.Query, pattern, id, s.Limit, s.Offset); err != nil {
+		return nil, 0, err
+	}
+	return accounts, total, nil
+}
+
+// Get retrieves the details of the account with the given id.
+func (r *accountRepository) Get(id int) (Account, error) {
+	const q = `SELECT id, email, display_name, email IS NULL AS guest,
+		EXISTS (SELECT 1 FROM two_factor WHERE account_id = accounts.id AND confirmed_at IS NOT NULL) AS two_factor_enabled,
+		verified_at, last_login_at, deletion_scheduled_at, deleted_at, created_at, updated_at
+		FROM accounts WHERE id = $1`
+
+	var account Account
+	if err := r.db.Get(&account, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, ErrAccountNotFound
+		}
+		return account, err
+	}
+	return account, nil
+}
+
+// Verify marks the email address of the account with the given id as verified and clears any
+// pending verification token.
+func (r *accountRepository) Verify(id int) error {
+	const q = `UPDATE accounts SET verified_at = COALESCE(verified_at, now()), verification_token = NULL, verification_token_expires_at = NULL WHERE id = $1 AND email IS NOT NULL`
+
+	res, err := r.db.Exec(q, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
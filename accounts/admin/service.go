@@ -0,0 +1,263 @@
+package admin
+
+import (
+	"strconv"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/auth"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/role"
+	"untitled_game/accounts/session"
+)
+
+// Service provides account management services for admins. Every action that changes an account is
+// recorded in the audit log along with the id of the admin that performed it. Actions on accounts
+// that hold more privileged roles than the admin are rejected with ErrOutranked.
+type Service interface {
+	Search(s Search) (Page, error)
+	Get(id int) (Details, error)
+	Sessions(id int) ([]session.Info, error)
+	Logout(adminID int, id int, meta session.Metadata) error
+	Verify(adminID int, id int, meta session.Metadata) error
+	ResetPassword(adminID int, id int, meta session.Metadata) error
+	Bans(id int) ([]ban.Ban, error)
+	Ban(adminID int, id int, b ban.NewBan, meta session.Metadata) (ban.Ban, error)
+	LiftBans(adminID int, id int, meta session.Metadata) error
+	Roles(id int) (role.Roles, error)
+	SetRoles(adminID int, id int, roles []string, meta session.Metadata) (role.Roles, error)
+	Unlock(adminID int, u auth.Unlock, meta session.Metadata) error
+}
+
+type sessionStore interface {
+	List(id int) ([]session.Info, error)
+	RemoveAll(sess session.Session) error
+}
+
+// resetter emails password reset links to account owners.
+type resetter interface {
+	RequestReset(email string) error
+}
+
+// unlocker clears the failed login attempts of account emails and client ip addresses.
+type unlocker interface {
+	Unlock(u auth.Unlock) error
+}
+
+type banService interface {
+	Ban(id int, b ban.NewBan) (ban.Ban, error)
+	Lift(id int) error
+	List(id int) ([]ban.Ban, error)
+}
+
+type roleService interface {
+	Get(id int) (role.Roles, error)
+	Set(id int, roles []string) (role.Roles, error)
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+type service struct {
+	sess     sessionStore
+	accounts AccountRepository
+	resetter resetter
+	unlocker unlocker
+	bans     banService
+	roles    roleService
+	audit    auditor
+}
+
+// NewService creates a new admin service.
+func NewService(sess sessionStore, accounts AccountRepository, resetter resetter, unlocker unlocker, bans banService, roles roleService, audit auditor) Service {
+	return &service{sess, accounts, resetter, unlocker, bans, roles, audit}
+}
+
+// Search retrieves a page of accounts that match the search query.
+func (s *service) Search(search Search) (Page, error) {
+	accounts, total, err := s.accounts.Search(search)
+	if err != nil {
+		return Page{}, err
+	}
+	return Page{accounts, total, search.Limit, search.Offset}, nil
+}
+
+// Get retrieves the details of the account with the given id along with its roles and bans.
+func (s *service) Get(id int) (Details, error) {
+	account, err := s.accounts.Get(id)
+	if err != nil {
+		return Details{}, err
+	}
+
+	roles, err := s.roles.Get(id)
+	if err != nil {
+		return Details{}, err
+	}
+
+	bans, err := s.bans.List(id)
+	if err != nil {
+		return Details{}, err
+	}
+	return Details{account, roles, bans}, nil
+}
+
+// Sessions retrieves the active sessions of the account with the given id.
+func (s *service) Sessions(id int) ([]session.Info, error) {
+	if _, err := s.accounts.Get(id); err != nil {
+		return nil, err
+	}
+	return s.sess.List(id)
+}
+
+// Logout removes all sessions of the account with the given id.
+func (s *service) Logout(adminID int, id int, meta session.Metadata) error {
+	if _, err := s.accounts.Get(id); err != nil {
+		return err
+	}
+	if err := s.checkRank(adminID, id); err != nil {
+		return err
+	}
+
+	if err := s.sess.RemoveAll(session.Session{ID: id}); err != nil {
+		return err
+	}
+	s.record(audit.EventAdminLogout, adminID, id, meta, nil)
+	return nil
+}
+
+// Verify marks the email address of the account with the given id as verified.
+func (s *service) Verify(adminID int, id int, meta session.Metadata) error {
+	account, err := s.accounts.Get(id)
+	if err != nil {
+		return err
+	}
+	if account.Email == nil {
+		return ErrGuestAccount
+	}
+	if err := s.checkRank(adminID, id); err != nil {
+		return err
+	}
+
+	if err := s.accounts.Verify(id); err != nil {
+		return err
+	}
+	s.record(audit.EventAdminVerify, adminID, id, meta, nil)
+	return nil
+}
+
+// ResetPassword emails a password reset link to the email address of the account with the given id.
+func (s *service) ResetPassword(adminID int, id int, meta session.Metadata) error {
+	account, err := s.accounts.Get(id)
+	if err != nil {
+		return err
+	}
+	if account.Email == nil {
+		return ErrGuestAccount
+	}
+	if err := s.checkRank(adminID, id); err != nil {
+		return err
+	}
+
+	if err := s.resetter.RequestReset(*account.Email); err != nil {
+		return err
+	}
+	s.record(audit.EventAdminResetPassword, adminID, id, meta, nil)
+	return nil
+}
+
+// Bans retrieves all bans of the account with the given id.
+func (s *service) Bans(id int) ([]ban.Ban, error) {
+	return s.bans.List(id)
+}
+
+// Ban bans the account with the given id on behalf of the admin with the given id.
+func (s *service) Ban(adminID int, id int, b ban.NewBan, meta session.Metadata) (ban.Ban, error) {
+	if err := s.checkRank(adminID, id); err != nil {
+		return ban.Ban{}, err
+	}
+
+	b.IssuedBy = "account:" + strconv.Itoa(adminID)
+
+	created, err := s.bans.Ban(id, b)
+	if err != nil {
+		return ban.Ban{}, err
+	}
+
+	s.record(audit.EventAdminBan, adminID, id, meta, created)
+	return created, nil
+}
+
+// LiftBans lifts the bans of the account with the given id.
+func (s *service) LiftBans(adminID int, id int, meta session.Metadata) error {
+	if err := s.checkRank(adminID, id); err != nil {
+		return err
+	}
+
+	if err := s.bans.Lift(id); err != nil {
+		return err
+	}
+	s.record(audit.EventAdminLiftBans, adminID, id, meta, nil)
+	return nil
+}
+
+// Roles retrieves the roles of the account with the given id.
+func (s *service) Roles(id int) (role.Roles, error) {
+	return s.roles.Get(id)
+}
+
+// SetRoles replaces the roles of the account with the given id.
+func (s *service) SetRoles(adminID int, id int, roles []string, meta session.Metadata) (role.Roles, error) {
+	if err := s.checkRank(adminID, id); err != nil {
+		return role.Roles{}, err
+	}
+
+	r, err := s.roles.Set(id, roles)
+	if err != nil {
+		return role.Roles{}, err
+	}
+
+	s.record(audit.EventAdminSetRoles, adminID, id, meta, role.Assignment{Roles: r.Roles})
+	return r, nil
+}
+
+// Unlock clears the failed login attempts of an account email and a client ip address.
+func (s *service) Unlock(adminID int, u auth.Unlock, meta session.Metadata) error {
+	if err := s.unlocker.Unlock(u); err != nil {
+		return err
+	}
+	s.record(audit.EventAdminUnlock, adminID, 0, meta, u)
+	return nil
+}
+
+// checkRank returns ErrOutranked if the account with the given id holds more privileged roles than
+// the admin with the given id. Every action that targets an account is checked, so that moderators
+// and support staff cannot act on admins.
+func (s *service) checkRank(adminID int, id int) error {
+	actor, err := s.roles.Get(adminID)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.roles.Get(id)
+	if err != nil {
+		return err
+	}
+	if target.Rank() > actor.Rank() {
+		return ErrOutranked
+	}
+	return nil
+}
+
+// record records an audit event for an action that was performed by the admin with the given id on
+// the account with the given id. The action has already been applied, so a failure to record it is
+// logged by the audit log rather than returned.
+func (s *service) record(eventType string, adminID int, id int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   adminID,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
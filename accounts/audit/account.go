@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Types of events that are recorded in the audit log.
+const (
+	EventLogin                = "login.succeeded"
+	EventLoginFailed          = "login.failed"
+	EventLoginChallenged      = "login.challenged"
+	EventSecondFactorFailed   = "login.second_factor_failed"
+	EventRefreshTokenReused   = "session.refresh_token_reused"
+	EventLogout               = "session.logout"
+	EventSessionRevoked       = "session.revoked"
+	EventSessionsRevoked      = "sessions.revoked"
+	EventRegistered           = "account.registered"
+	EventGuestUpgraded        = "account.upgraded"
+	EventDeletionScheduled    = "account.deletion_scheduled"
+	EventPasswordChanged      = "password.changed"
+	EventTwoFactorEnabled     = "two_factor.enabled"
+	EventTwoFactorDisabled    = "two_factor.disabled"
+	EventEmailChangeRequested = "email.change_requested"
+	EventEmailChanged         = "email.changed"
+	EventEmailChangeReverted  = "email.change_reverted"
+	EventInvitesMinted        = "invites.mint"
+	EventInvitesRevoked       = "invites.revoke"
+	EventAdminLogout          = "accounts.logout"
+	EventAdminVerify          = "accounts.verify"
+	EventAdminResetPassword   = "accounts.reset_password"
+	EventAdminBan             = "accounts.ban"
+	EventAdminLiftBans        = "accounts.unban"
+	EventAdminUnlock          = "accounts.unlock"
+	EventAdminSetRoles        = "roles.set"
+)
+
+// Entry represents an event that is added to the audit log. The actor is the account that caused the
+// event and the account is the account that the event affects. Either may be zero if the event is
+// not tied to a known account, such as a failed login for an unregistered email. The payload is
+// stored as a json object.
+type Entry struct {
+	Type      string
+	ActorID   int
+	AccountID int
+	IP        string
+	UserAgent string
+	Payload   interface{}
+}
+
+// Event represents an event that was recorded in the audit log.
+type Event struct {
+	ID        int64           `json:"id" db:"id"`
+	Type      string          `json:"type" db:"type"`
+	ActorID   *int            `json:"actor_id" db:"actor_id"`
+	AccountID *int            `json:"account_id" db:"account_id"`
+	IP        string          `json:"ip" db:"ip"`
+	UserAgent string          `json:"user_agent" db:"user_agent"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Query represents a filter for audit log events. Zero values are not used for filtering, so an
+// empty query matches every event. Events are returned most recent first.
+type Query struct {
+	AccountID int
+	Type      string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// Validate validates audit log query data.
+func (q Query) Validate() error {
+	return validation.ValidateStruct(&q,
+		validation.Field(&q.AccountID, validation.Min(0)),
+		validation.Field(&q.Type, validation.RuneLength(0, 100)),
+		validation.Field(&q.To, validation.By(func(interface{}) error {
+			if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+				return validation.NewError("validation_to_before_from", "must be after the start of the time range")
+			}
+			return nil
+		})),
+		validation.Field(&q.Limit, validation.Min(1), validation.Max(500)),
+		validation.Field(&q.Offset, validation.Min(0)),
+	)
+}
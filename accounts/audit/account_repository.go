@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Insert(e Entry) error
+	List(q Query) ([]Event, error)
+	ListAccount(id int) ([]Event, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Insert appends an event to the audit log.
+func (r *accountRepository) Insert(e Entry) error {
+	const q = `INSERT INTO audit_events (type, actor_id, account_id, ip, user_agent, payload) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	payload := []byte("{}")
+	if e.Payload != nil {
+		var err error
+		if payload, err = json.Marshal(e.Payload); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.Exec(q, e.Type, nullID(e.ActorID), nullID(e.AccountID), e.IP, e.UserAgent, string(payload))
+	return err
+}
+
+// List retrieves the audit log events that match the query.
+func (r *accountRepository) List(q Query) ([]Event, error) {
+	const query = `SELECT id, type, actor_id, account_id, ip, user_agent, payload, created_at FROM audit_events
+		WHERE ($1::integer IS NULL OR account_id = $1)
+		AND ($2 = '' OR type = $2)
+		AND ($3::timestamptz IS NULL OR created_at >= $3)
+		AND ($4::timestamptz IS NULL OR created_at < $4)
+		ORDER BY created_at DESC, id DESC LIMIT $5 OFFSET $6`
+
+	events := []Event{}
+	if err := r.db.Select(&events, query, nullID(q.AccountID), q.Type, nullTime(q.From), nullTime(q.To), q.Limit, q.Offset); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListAccount retrieves every event that was caused by or affects the account with the given id,
+// oldest first.
+func (r *accountRepository) ListAccount(id int) ([]Event, error) {
+	const q = `SELECT id, type, actor_id, account_id, ip, user_agent, payload, created_at FROM audit_events
+		WHERE account_id = $1 OR actor_id = $1 ORDER BY created_at, id`
+
+	events := []Event{}
+	if err := r.db.Select(&events, q, id); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// nullID converts an account id to a nullable id, where zero is null.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// nullTime converts a time to a nullable time, where the zero time is null.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
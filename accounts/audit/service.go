@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"log"
+)
+
+// Service provides security audit log services.
+type Service interface {
+	Record(e Entry)
+	List(q Query) ([]Event, error)
+	History(id int) ([]Event, error)
+}
+
+type service struct {
+	accounts AccountRepository
+	log      *log.Logger
+}
+
+// NewService creates a new audit log service. Events that cannot be recorded are written to the
+// logger instead.
+func NewService(accounts AccountRepository, log *log.Logger) Service {
+	return &service{accounts, log}
+}
+
+// Record appends an event to the audit log. Recording never fails the action that is being audited,
+// so an event that cannot be stored is logged along with the error.
+func (s *service) Record(e Entry) {
+	if err := s.accounts.Insert(e); err != nil {
+		s.log.Printf("could not record audit event %s for account %d: %v", e.Type, e.AccountID, err)
+	}
+}
+
+// List retrieves the audit log events that match the query.
+func (s *service) List(q Query) ([]Event, error) {
+	return s.accounts.List(q)
+}
+
+// History retrieves every event that was caused by or affects the account with the given id.
+func (s *service) History(id int) ([]Event, error) {
+	return s.accounts.ListAccount(id)
+}
@@ -6,17 +6,22 @@ import (
 )
 
 // Account represents account info that is retrieved from the account repository as part of the
-// authentication process. The retrieved account password is compared to the supplied password
-// using bcrypt to check for a match.
+// authentication process. The retrieved account password hash is compared to the supplied password
+// to check for a match.
 type Account struct {
 	ID       int    `json:"id"`
 	Password string `json:"password"`
 }
 
 // Credentials represents an email and password combination that is used to authenticate a user.
+// The optional device name is stored with the session that is created upon successful login. The
+// captcha response is required when bot verification is enabled for the endpoint and the client has
+// crossed its activity threshold.
 type Credentials struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Device   string `json:"device"`
+	Captcha  string `json:"captcha"`
 }
 
 // Validate validates account credentials data.
@@ -24,5 +29,49 @@ func (c Credentials) Validate() error {
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.Email, validation.Required, is.Email),
 		validation.Field(&c.Password, validation.Required),
+		validation.Field(&c.Device, validation.RuneLength(0, 64)),
+		validation.Field(&c.Captcha, validation.RuneLength(0, 4096)),
+	)
+}
+
+// SecondFactor represents a login challenge token along with a code from an authenticator app or a
+// recovery code that is used to complete the login.
+type SecondFactor struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// Validate validates second factor data.
+func (sf SecondFactor) Validate() error {
+	return validation.ValidateStruct(&sf,
+		validation.Field(&sf.Challenge, validation.Required),
+		validation.Field(&sf.Code, validation.Required),
+	)
+}
+
+// Refresh represents a refresh token that is exchanged for new auth tokens.
+type Refresh struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Validate validates refresh data.
+func (r Refresh) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.RefreshToken, validation.Required),
+	)
+}
+
+// Unlock represents an account email and a client ip address whose failed login attempts should be
+// cleared. At least one of the two must be supplied.
+type Unlock struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
+// Validate validates unlock data.
+func (u Unlock) Validate() error {
+	return validation.ValidateStruct(&u,
+		validation.Field(&u.Email, validation.Required.When(u.IP == ""), is.Email),
+		validation.Field(&u.IP, is.IP),
 	)
 }
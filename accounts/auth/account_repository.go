@@ -3,6 +3,7 @@ package auth
 import (
 	"database/sql"
 	"errors"
+	"untitled_game/core/normalize"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -13,6 +14,8 @@ var ErrAccountNotFound = errors.New("account not found")
 // AccountRepository provides methods for interacting with an account store.
 type AccountRepository interface {
 	GetByEmail(email string) (Account, error)
+	SetPassword(id int, password string) error
+	CancelDeletion(id int) error
 }
 
 type accountRepository struct {
@@ -24,12 +27,16 @@ func NewAccountRepository(db *sqlx.DB) AccountRepository {
 	return &accountRepository{db}
 }
 
-// GetByEmail retrieves an account from the database by its email.
+// GetByEmail retrieves an account from the database by its email. If no account has exactly the
+// given email, then the account whose email has the same canonical form is retrieved instead.
+// Accounts whose scheduled deletion time has passed are not found.
 func (r *accountRepository) GetByEmail(email string) (Account, error) {
-	const q = `SELECT id, password FROM accounts WHERE email = $1`
+	const q = `SELECT id, password FROM accounts
+		WHERE (email = $1 OR normalized_email = $2) AND (deletion_scheduled_at IS NULL OR deletion_scheduled_at > now())
+		ORDER BY email = $1 DESC LIMIT 1`
 
 	var account Account
-	if err := r.db.Get(&account, q, email); err != nil {
+	if err := r.db.Get(&account, q, email, normalize.Email(email)); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return account, ErrAccountNotFound
 		}
@@ -37,3 +44,20 @@ func (r *accountRepository) GetByEmail(email string) (Account, error) {
 	}
 	return account, nil
 }
+
+// SetPassword updates the hashed password of the account with the given id.
+func (r *accountRepository) SetPassword(id int, password string) error {
+	const q = `UPDATE accounts SET password = $2 WHERE id = $1`
+
+	_, err := r.db.Exec(q, id, password)
+	return err
+}
+
+// CancelDeletion cancels the scheduled deletion of the account with the given id, unless the
+// scheduled deletion time has already passed.
+func (r *accountRepository) CancelDeletion(id int) error {
+	const q = `UPDATE accounts SET deletion_scheduled_at = NULL WHERE id = $1 AND deletion_scheduled_at > now()`
+
+	_, err := r.db.Exec(q, id)
+	return err
+}
@@ -2,88 +2,622 @@ package auth
 
 import (
 	"errors"
+	"log"
+	"math"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/loginhistory"
 	"untitled_game/accounts/session"
+	"untitled_game/accounts/throttle"
+	"untitled_game/accounts/twofactor"
+	"untitled_game/core/captcha"
+	"untitled_game/core/hasher"
+	"untitled_game/core/jwt"
+	"untitled_game/core/normalize"
+	"untitled_game/core/token"
+)
+
+// dummyPassword is hashed to create a hash that does not correspond to any account. When account
+// enumeration protection is enabled, login attempts for unknown emails are compared against this
+// hash so that they take as long as attempts for registered emails.
+const dummyPassword = "untitled_game dummy password"
 
-	"golang.org/x/crypto/bcrypt"
+// Names of the bot verification endpoints that credentials are checked against.
+const (
+	loginEndpoint        = "login"
+	authenticateEndpoint = "authenticate"
 )
 
 // ErrInvalidCredentials is used when authentication fails due to an incorrect account email
 // address and password combination being supplied.
 var ErrInvalidCredentials = errors.New("invalid account credentials")
 
+// ErrInvalidChallenge is used when a second factor is supplied for a login challenge that does not
+// exist or has expired.
+var ErrInvalidChallenge = errors.New("invalid login challenge")
+
+// SecondFactorRequiredError is used when the supplied account credentials are valid, but the account
+// has two-factor authentication enabled. The challenge token must be exchanged for a session along
+// with a valid second factor.
+type SecondFactorRequiredError struct {
+	Challenge string
+}
+
+// Error implements the error interface.
+func (e *SecondFactorRequiredError) Error() string {
+	return "second factor required"
+}
+
+// ThrottledError is used when a login attempt is rejected without checking the credentials because
+// there have been too many failed attempts for the account email or the client ip address, or too
+// many failed second factors for the account.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *ThrottledError) Error() string {
+	return "too many failed login attempts"
+}
+
+// GameToken represents a signed access token that is issued to game clients following a successful
+// authentication attempt. Game servers verify the token offline using the published signing keys.
+type GameToken struct {
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
 // Service provides authentication related services.
 type Service interface {
-	Login(creds Credentials) (session.Token, error)
-	Logout(sess session.Session) error
-	Authenticate(creds Credentials) (session.Token, error)
+	Login(creds Credentials, meta session.Metadata) (session.Token, error)
+	LoginSecondFactor(sf SecondFactor, meta session.Metadata) (session.Token, error)
+	Refresh(r Refresh, meta session.Metadata) (session.Token, error)
+	Logout(sess session.Session, meta session.Metadata) error
+	RevokeSession(sess session.Session, key string, meta session.Metadata) error
+	RevokeSessions(sess session.Session, meta session.Metadata) error
+	Authenticate(creds Credentials, meta session.Metadata) (GameToken, error)
+	AuthenticateSecondFactor(sf SecondFactor, meta session.Metadata) (GameToken, error)
+	Unlock(u Unlock) error
+}
+
+// secondFactor verifies the second authentication factor of accounts that have two-factor
+// authentication enabled.
+type secondFactor interface {
+	Enabled(id int) (bool, error)
+	Verify(id int, code string) error
+}
+
+// throttler tracks failed login attempts and locks out keys that fail too often.
+type throttler interface {
+	Wait(key string) (time.Duration, error)
+	Fail(key string, policy throttle.Policy) (time.Duration, error)
+	Reset(key string) error
+}
+
+// banChecker checks whether an account is banned.
+type banChecker interface {
+	Check(id int) error
+}
+
+// permissionLoader loads the permissions that are granted to an account by its roles.
+type permissionLoader interface {
+	Permissions(id int) ([]string, error)
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+// loginRecorder records successful logins in the login history of an account.
+type loginRecorder interface {
+	Record(id int, l loginhistory.Login)
+}
+
+// botGuard checks whether a request must pass bot verification and verifies its captcha response.
+type botGuard interface {
+	Check(endpoint string, response string, ip string) error
+}
+
+// tokenIssuer issues signed access tokens for game servers.
+type tokenIssuer interface {
+	Issue(claims jwt.Claims) (jwt.Token, error)
+}
+
+// ServiceConfig represents configuration options for an auth service. The policies determine when
+// failed logins for an account email or a client ip address, and failed second factors for an
+// account, are throttled. When enumeration protection is enabled, a password hash comparison is
+// performed even if no account exists with the supplied email so that response timing does not
+// reveal which emails are registered.
+type ServiceConfig struct {
+	EmailPolicy        throttle.Policy
+	IPPolicy           throttle.Policy
+	SecondFactorPolicy throttle.Policy
+	ProtectEnumeration bool
 }
 
 type service struct {
-	sess     session.Store
-	accounts AccountRepository
+	sess         session.Store
+	accounts     AccountRepository
+	hasher       hasher.Hasher
+	secondFactor secondFactor
+	throttle     throttler
+	tokens       tokenIssuer
+	bans         banChecker
+	permissions  permissionLoader
+	audit        auditor
+	logins       loginRecorder
+	bots         botGuard
+	log          *log.Logger
+	emailPolicy  throttle.Policy
+	ipPolicy     throttle.Policy
+	sfPolicy     throttle.Policy
+	protect      bool
+
+	dummyOnce sync.Once
+	dummyHash string
 }
 
 // NewService creates a new auth service.
-func NewService(sess session.Store, accounts AccountRepository) Service {
-	return &service{sess, accounts}
+func NewService(sess session.Store, accounts AccountRepository, hasher hasher.Hasher, secondFactor secondFactor, throttle throttler, tokens tokenIssuer, bans banChecker, permissions permissionLoader, audit auditor, logins loginRecorder, bots botGuard, log *log.Logger, cfg ServiceConfig) Service {
+	return &service{
+		sess:         sess,
+		accounts:     accounts,
+		hasher:       hasher,
+		secondFactor: secondFactor,
+		throttle:     throttle,
+		tokens:       tokens,
+		bans:         bans,
+		permissions:  permissions,
+		audit:        audit,
+		logins:       logins,
+		bots:         bots,
+		log:          log,
+		emailPolicy:  cfg.EmailPolicy,
+		ipPolicy:     cfg.IPPolicy,
+		sfPolicy:     cfg.SecondFactorPolicy,
+		protect:      cfg.ProtectEnumeration,
+	}
 }
 
 // Login authenticates account credentials. If successful, a new session is added to the session
-// store for the authenticated user.
-func (s *service) Login(creds Credentials) (session.Token, error) {
-	account, err := s.accounts.GetByEmail(strings.ToLower(creds.Email))
+// store for the authenticated user along with the supplied session metadata. If the account has
+// two-factor authentication enabled, then a SecondFactorRequiredError is returned instead. Failed
+// attempts are tracked per account email and per client ip address, and a ThrottledError is
+// returned while either is locked.
+func (s *service) Login(creds Credentials, meta session.Metadata) (session.Token, error) {
+	account, err := s.checkCredentials(loginEndpoint, creds, meta)
 	if err != nil {
-		if errors.Is(err, ErrAccountNotFound) {
-			return session.Token{}, ErrInvalidCredentials
-		}
 		return session.Token{}, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(creds.Password)); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return session.Token{}, ErrInvalidCredentials
-		}
+	if err := s.checkSecondFactor(account, creds.Device, meta); err != nil {
 		return session.Token{}, err
 	}
 
-	sess, err := session.New(account.ID)
+	t, err := s.addSession(account.ID, meta)
 	if err != nil {
 		return session.Token{}, err
 	}
+	s.record(audit.EventLogin, account.ID, meta, loginMethod{"password"})
+	return t, nil
+}
 
-	if err := s.sess.Add(sess); err != nil {
+// LoginSecondFactor completes a login challenge with a second authentication factor. If successful,
+// a new session is added to the session store for the authenticated user.
+func (s *service) LoginSecondFactor(sf SecondFactor, meta session.Metadata) (session.Token, error) {
+	c, err := s.completeChallenge(sf, meta)
+	if err != nil {
 		return session.Token{}, err
 	}
-	return session.CreateToken(sess), nil
+
+	meta.Device = c.Device
+	t, err := s.addSession(c.ID, meta)
+	if err != nil {
+		return session.Token{}, err
+	}
+	s.record(audit.EventLogin, c.ID, meta, loginMethod{"second_factor"})
+	return t, nil
+}
+
+// Refresh exchanges a refresh token for new auth tokens. If the refresh token has already been
+// exchanged, then the session that it was issued for is removed from the session store and the reuse
+// is recorded in the audit log.
+func (s *service) Refresh(r Refresh, meta session.Metadata) (session.Token, error) {
+	sess, err := session.ParseToken(r.RefreshToken)
+	if err != nil {
+		return session.Token{}, session.ErrInvalidRefreshToken
+	}
+
+	t, err := s.sess.Refresh(sess)
+	if errors.Is(err, session.ErrRefreshTokenReused) {
+		s.record(audit.EventRefreshTokenReused, sess.ID, meta, nil)
+	}
+	return t, err
 }
 
 // Logout logs the user out of the current session by deleting the session from the session store.
-func (s *service) Logout(sess session.Session) error {
-	return s.sess.Remove(sess)
+func (s *service) Logout(sess session.Session, meta session.Metadata) error {
+	if err := s.sess.Remove(sess); err != nil {
+		return err
+	}
+
+	s.record(audit.EventLogout, sess.ID, meta, nil)
+	return nil
+}
+
+// RevokeSession deletes the session with the given key from the sessions of the account that owns
+// the current session. If no such session exists, then session.ErrSessionNotFound is returned.
+func (s *service) RevokeSession(sess session.Session, key string, meta session.Metadata) error {
+	if err := s.sess.Remove(session.Session{ID: sess.ID, Key: key}); err != nil {
+		return err
+	}
+
+	s.record(audit.EventSessionRevoked, sess.ID, meta, revokedSession{key})
+	return nil
+}
+
+// RevokeSessions deletes all sessions of the account that owns the current session, including the
+// current session.
+func (s *service) RevokeSessions(sess session.Session, meta session.Metadata) error {
+	if err := s.sess.RemoveAll(sess); err != nil {
+		return err
+	}
+
+	s.record(audit.EventSessionsRevoked, sess.ID, meta, nil)
+	return nil
+}
+
+// Authenticate authenticates account credentials. If successful, a signed access token is issued
+// for the authenticated user without adding a session to the session store, so that game servers
+// can verify the user without contacting the accounts service. If the account has two-factor
+// authentication enabled, then a SecondFactorRequiredError is returned instead. Failed attempts are
+// tracked per account email and per client ip address, and a ThrottledError is returned while
+// either is locked.
+func (s *service) Authenticate(creds Credentials, meta session.Metadata) (GameToken, error) {
+	account, err := s.checkCredentials(authenticateEndpoint, creds, meta)
+	if err != nil {
+		return GameToken{}, err
+	}
+
+	if err := s.checkSecondFactor(account, creds.Device, meta); err != nil {
+		return GameToken{}, err
+	}
+
+	t, err := s.issueToken(account.ID, meta)
+	if err != nil {
+		return GameToken{}, err
+	}
+	s.record(audit.EventLogin, account.ID, meta, loginMethod{"game_token"})
+	return t, nil
+}
+
+// AuthenticateSecondFactor completes a login challenge with a second authentication factor. If
+// successful, a signed access token is issued for the authenticated user.
+func (s *service) AuthenticateSecondFactor(sf SecondFactor, meta session.Metadata) (GameToken, error) {
+	c, err := s.completeChallenge(sf, meta)
+	if err != nil {
+		return GameToken{}, err
+	}
+
+	t, err := s.issueToken(c.ID, meta)
+	if err != nil {
+		return GameToken{}, err
+	}
+	s.record(audit.EventLogin, c.ID, meta, loginMethod{"game_token_second_factor"})
+	return t, nil
+}
+
+// Unlock clears the failed login attempts of an account email and a client ip address, lifting any
+// lockout that is in place.
+func (s *service) Unlock(u Unlock) error {
+	if u.Email != "" {
+		if err := s.throttle.Reset(s.emailKey(u.Email).key); err != nil {
+			return err
+		}
+	}
+	if u.IP != "" {
+		if err := s.throttle.Reset(s.ipKey(u.IP).key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// throttleKey represents a key that failed login attempts are tracked by along with the policy that
+// determines when the key is locked.
+type throttleKey struct {
+	key    string
+	policy throttle.Policy
 }
 
-// Authenticate authenticates account credentials. If successful, a new session is returned for the
-// authenticated user without adding the session to the session store.
-func (s *service) Authenticate(creds Credentials) (session.Token, error) {
+// emailKey returns the throttle key for an account email. Emails are throttled by their canonical
+// form, so that failed attempts cannot be spread across different forms of the same email.
+func (s *service) emailKey(email string) throttleKey {
+	return throttleKey{"login:email:" + normalize.Email(email), s.emailPolicy}
+}
+
+// ipKey returns the throttle key for a client ip address.
+func (s *service) ipKey(ip string) throttleKey {
+	return throttleKey{"login:ip:" + ip, s.ipPolicy}
+}
+
+// secondFactorKey returns the throttle key for the second factor of an account. Failed second
+// factors are tracked per account rather than per challenge, since a new challenge can be created
+// with every password login.
+func (s *service) secondFactorKey(id int) throttleKey {
+	return throttleKey{"login:second_factor:" + strconv.Itoa(id), s.sfPolicy}
+}
+
+// checkCredentials retrieves the account with the credentials email and compares the account
+// password to the credentials password. If the account password was hashed with outdated
+// parameters, then it is rehashed with the current parameters and stored. If the account is
+// banned, then a ban.BannedError is returned. Failed attempts are tracked per account email and per
+// client ip address. If either is locked, then a ThrottledError is returned without checking the
+// credentials. If the client must pass bot verification on the endpoint, then captcha.ErrRequired
+// or captcha.ErrFailed is returned unless the captcha response is valid. Otherwise, a failed
+// attempt is recorded for each key when the credentials are invalid, and the failed attempts for the
+// account email are cleared when the credentials are valid. Rejected attempts are recorded in the
+// audit log.
+func (s *service) checkCredentials(endpoint string, creds Credentials, meta session.Metadata) (Account, error) {
+	keys := []throttleKey{s.emailKey(creds.Email), s.ipKey(meta.IP)}
+
+	var wait time.Duration
+	for _, k := range keys {
+		w, err := s.throttle.Wait(k.key)
+		if err != nil {
+			return Account{}, err
+		}
+		if w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		s.record(audit.EventLoginFailed, 0, meta, failure(0, creds.Email, "throttled"))
+		return Account{}, &ThrottledError{wait}
+	}
+
+	if err := s.bots.Check(endpoint, creds.Captcha, meta.IP); err != nil {
+		if errors.Is(err, captcha.ErrFailed) {
+			s.record(audit.EventLoginFailed, 0, meta, failure(0, creds.Email, "captcha"))
+		}
+		return Account{}, err
+	}
+
 	account, err := s.accounts.GetByEmail(strings.ToLower(creds.Email))
 	if err != nil {
 		if errors.Is(err, ErrAccountNotFound) {
-			return session.Token{}, ErrInvalidCredentials
+			if s.protect {
+				s.hasher.Compare(s.dummy(), creds.Password)
+			}
+			s.record(audit.EventLoginFailed, 0, meta, failure(0, creds.Email, "unknown_account"))
+			return Account{}, s.recordFailure(keys)
 		}
-		return session.Token{}, err
+		return Account{}, err
+	}
+
+	if err := s.hasher.Compare(account.Password, creds.Password); err != nil {
+		if errors.Is(err, hasher.ErrMismatch) {
+			s.record(audit.EventLoginFailed, account.ID, meta, failure(account.ID, creds.Email, "invalid_password"))
+			return Account{}, s.recordFailure(keys)
+		}
+		return Account{}, err
+	}
+
+	if s.hasher.NeedsRehash(account.Password) {
+		s.rehash(account.ID, creds.Password)
+	}
+
+	if err := s.bans.Check(account.ID); err != nil {
+		var bannedErr *ban.BannedError
+		if errors.As(err, &bannedErr) {
+			s.record(audit.EventLoginFailed, account.ID, meta, failure(account.ID, creds.Email, "banned"))
+		}
+		return Account{}, err
+	}
+
+	if err := s.throttle.Reset(s.emailKey(creds.Email).key); err != nil {
+		return Account{}, err
+	}
+	return account, nil
+}
+
+// rehash hashes the password of an account with the current hasher parameters and stores it. A
+// failure is logged rather than returned, since the credentials are valid and the password is
+// rehashed again on the next login.
+func (s *service) rehash(id int, password string) {
+	hashedPw, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.accounts.SetPassword(id, hashedPw)
+	}
+	if err != nil {
+		s.log.Printf("could not rehash password of account %d: %v", id, err)
+	}
+}
+
+// dummy returns a hash of the dummy password that was created by the hasher with its current
+// parameters. The hash is created on first use. If hashing fails, then an empty hash is returned and
+// comparisons against it fail immediately.
+func (s *service) dummy() string {
+	s.dummyOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash(dummyPassword)
+	})
+	return s.dummyHash
+}
+
+// recordFailure records a failed login attempt for each of the throttle keys. ErrInvalidCredentials
+// is returned once the failures have been recorded.
+func (s *service) recordFailure(keys []throttleKey) error {
+	for _, k := range keys {
+		if _, err := s.throttle.Fail(k.key, k.policy); err != nil {
+			return err
+		}
+	}
+	return ErrInvalidCredentials
+}
+
+// checkSecondFactor returns a SecondFactorRequiredError containing a new login challenge if the
+// account has two-factor authentication enabled.
+func (s *service) checkSecondFactor(account Account, device string, meta session.Metadata) error {
+	enabled, err := s.secondFactor.Enabled(account.ID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(creds.Password)); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return session.Token{}, ErrInvalidCredentials
+	challenge, err := s.sess.AddChallenge(session.Challenge{ID: account.ID, Device: device})
+	if err != nil {
+		return err
+	}
+	s.record(audit.EventLoginChallenged, account.ID, meta, nil)
+	return &SecondFactorRequiredError{challenge}
+}
+
+// completeChallenge verifies the second factor of a login challenge and removes the challenge once
+// it has been completed. Failed second factors are tracked per account, and a ThrottledError is
+// returned without checking the second factor while the account is locked. If two-factor
+// authentication was disabled while the challenge was pending, then the challenge is invalid. The
+// account is checked for bans again, since it may have been banned while the challenge was pending.
+// Rejected second factors are recorded in the audit log.
+func (s *service) completeChallenge(sf SecondFactor, meta session.Metadata) (session.Challenge, error) {
+	c, err := s.sess.GetChallenge(sf.Challenge)
+	if err != nil {
+		if errors.Is(err, session.ErrChallengeNotFound) {
+			return session.Challenge{}, ErrInvalidChallenge
 		}
+		return session.Challenge{}, err
+	}
+
+	key := s.secondFactorKey(c.ID)
+	wait, err := s.throttle.Wait(key.key)
+	if err != nil {
+		return session.Challenge{}, err
+	}
+	if wait > 0 {
+		s.record(audit.EventSecondFactorFailed, c.ID, meta, loginFailure{Reason: "throttled"})
+		return session.Challenge{}, &ThrottledError{wait}
+	}
+
+	if err := s.secondFactor.Verify(c.ID, sf.Code); err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			s.record(audit.EventSecondFactorFailed, c.ID, meta, nil)
+			if _, err := s.throttle.Fail(key.key, key.policy); err != nil {
+				return session.Challenge{}, err
+			}
+		case errors.Is(err, twofactor.ErrNotEnabled):
+			return session.Challenge{}, ErrInvalidChallenge
+		}
+		return session.Challenge{}, err
+	}
+
+	if err := s.throttle.Reset(key.key); err != nil {
+		return session.Challenge{}, err
+	}
+
+	if err := s.sess.RemoveChallenge(sf.Challenge); err != nil {
+		return session.Challenge{}, err
+	}
+
+	if err := s.bans.Check(c.ID); err != nil {
+		var bannedErr *ban.BannedError
+		if errors.As(err, &bannedErr) {
+			s.record(audit.EventLoginFailed, c.ID, meta, loginFailure{Reason: "banned"})
+		}
+		return session.Challenge{}, err
+	}
+	return c, nil
+}
+
+// loginMethod represents the payload of a successful login audit event.
+type loginMethod struct {
+	Method string `json:"method"`
+}
+
+// revokedSession represents the payload of a session revocation audit event.
+type revokedSession struct {
+	Key string `json:"key"`
+}
+
+// loginFailure represents the payload of a failed login audit event.
+type loginFailure struct {
+	EmailHash string `json:"email_hash,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// failure returns the payload of a failed login audit event for the account with the given id. The
+// supplied email is never stored as it is. If the account is not known, then the hash of the
+// canonical email is stored instead, which still allows attempts against the same email to be
+// correlated, and lets the event be scrubbed when an account with that email is purged.
+func failure(id int, email string, reason string) loginFailure {
+	if id != 0 {
+		return loginFailure{Reason: reason}
+	}
+	return loginFailure{token.Hash(normalize.Email(email)), reason}
+}
+
+// record records an audit event that was caused by and affects the account with the given id.
+func (s *service) record(eventType string, id int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
+
+// issueToken issues a signed access token for the account with the given id and records the login
+// in the login history of the account. Since the login has fully succeeded, any scheduled deletion
+// of the account is cancelled.
+func (s *service) issueToken(id int, meta session.Metadata) (GameToken, error) {
+	if err := s.accounts.CancelDeletion(id); err != nil {
+		return GameToken{}, err
+	}
+
+	t, err := s.tokens.Issue(jwt.Claims{Subject: strconv.Itoa(id), AccountID: id})
+	if err != nil {
+		return GameToken{}, err
+	}
+
+	s.logins.Record(id, loginhistory.NewLogin("", meta.IP, meta.UserAgent, meta.Device))
+
+	expiresIn := int(math.Ceil(time.Until(t.ExpiresAt).Seconds()))
+	return GameToken{t.Token, "Bearer", expiresIn}, nil
+}
+
+// addSession creates a new session for the account with the given id and adds it to the session
+// store. The permissions of the account are cached with the session. The login is recorded in the
+// login history of the account. Since the login has fully succeeded, any scheduled deletion of the
+// account is cancelled.
+func (s *service) addSession(id int, meta session.Metadata) (session.Token, error) {
+	if err := s.accounts.CancelDeletion(id); err != nil {
+		return session.Token{}, err
+	}
+
+	sess, err := session.New(id)
+	if err != nil {
 		return session.Token{}, err
 	}
 
-	sess, err := session.New(account.ID)
+	sess.Permissions, err = s.permissions.Permissions(id)
 	if err != nil {
 		return session.Token{}, err
 	}
-	return session.CreateToken(sess), nil
+
+	t, err := s.sess.Add(sess, meta)
+	if err != nil {
+		return session.Token{}, err
+	}
+
+	s.logins.Record(id, loginhistory.NewLogin(sess.Key, meta.IP, meta.UserAgent, meta.Device))
+	return t, nil
 }
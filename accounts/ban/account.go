@@ -0,0 +1,56 @@
+package ban
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Ban represents a ban of an account. A ban without an expiration time is permanent, while a ban
+// with an expiration time is a temporary suspension.
+type Ban struct {
+	ID        int        `json:"id" db:"id"`
+	AccountID int        `json:"account_id" db:"account_id"`
+	Reason    string     `json:"reason" db:"reason"`
+	IssuedBy  string     `json:"issued_by" db:"issued_by"`
+	StartsAt  time.Time  `json:"starts_at" db:"starts_at"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	LiftedAt  *time.Time `json:"lifted_at" db:"lifted_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NewBan represents the data required to ban an account. The ban starts immediately unless a start
+// time is supplied, and is permanent unless an expiration time is supplied. The issuer is set by
+// the admin service from the session of the moderator that issues the ban.
+type NewBan struct {
+	Reason    string     `json:"reason"`
+	IssuedBy  string     `json:"-"`
+	StartsAt  *time.Time `json:"starts_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Validate validates new ban data.
+func (b NewBan) Validate() error {
+	return validation.ValidateStruct(&b,
+		validation.Field(&b.Reason, validation.Required, validation.RuneLength(1, 500)),
+		validation.Field(&b.ExpiresAt, validation.By(func(interface{}) error {
+			if b.ExpiresAt == nil {
+				return nil
+			}
+			start := time.Now()
+			if b.StartsAt != nil {
+				start = *b.StartsAt
+			}
+			if !b.ExpiresAt.After(start) {
+				return validation.NewError("validation_expires_before_start", "must be after the start time")
+			}
+			return nil
+		})),
+	)
+}
+
+// Details represents the ban information that is shown to a banned user.
+type Details struct {
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
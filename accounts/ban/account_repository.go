@@ -0,0 +1,100 @@
+package ban
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"untitled_game/core/postgres"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrNotBanned is used when a ban does not exist or is not in effect.
+var ErrNotBanned = errors.New("account not banned")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Create(id int, b NewBan) (Ban, error)
+	Active(id int) (Ban, error)
+	List(id int) ([]Ban, error)
+	Lift(id int) error
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Create inserts a new ban for the account with the given id.
+func (r *accountRepository) Create(id int, b NewBan) (Ban, error) {
+	const q = `INSERT INTO bans (account_id, reason, issued_by, starts_at, expires_at) VALUES ($1, $2, $3, COALESCE($4, now()), $5) RETURNING id, account_id, reason, issued_by, starts_at, expires_at, lifted_at, created_at`
+
+	var ban Ban
+	if err := r.db.Get(&ban, q, id, b.Reason, b.IssuedBy, nullTime(b.StartsAt), nullTime(b.ExpiresAt)); err != nil {
+		if postgres.IsForeignKeyViolationError(err) {
+			return ban, ErrAccountNotFound
+		}
+		return ban, err
+	}
+	return ban, nil
+}
+
+// Active retrieves the ban that is in effect for the account with the given id. If several bans are
+// in effect, then the one that lasts the longest is returned.
+func (r *accountRepository) Active(id int) (Ban, error) {
+	const q = `SELECT id, account_id, reason, issued_by, starts_at, expires_at, lifted_at, created_at FROM bans WHERE account_id = $1 AND lifted_at IS NULL AND starts_at <= now() AND (expires_at IS NULL OR expires_at > now()) ORDER BY expires_at DESC NULLS FIRST LIMIT 1`
+
+	var ban Ban
+	if err := r.db.Get(&ban, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ban, ErrNotBanned
+		}
+		return ban, err
+	}
+	return ban, nil
+}
+
+// List retrieves all bans of the account with the given id, most recent first.
+func (r *accountRepository) List(id int) ([]Ban, error) {
+	const q = `SELECT id, account_id, reason, issued_by, starts_at, expires_at, lifted_at, created_at FROM bans WHERE account_id = $1 ORDER BY created_at DESC`
+
+	bans := []Ban{}
+	if err := r.db.Select(&bans, q, id); err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+// Lift lifts all bans of the account with the given id that are in effect or have not started yet.
+func (r *accountRepository) Lift(id int) error {
+	const q = `UPDATE bans SET lifted_at = now() WHERE account_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > now())`
+
+	res, err := r.db.Exec(q, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotBanned
+	}
+	return nil
+}
+
+// nullTime converts an optional time to a nullable time.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -0,0 +1,84 @@
+package ban
+
+import (
+	"errors"
+	"time"
+	"untitled_game/accounts/session"
+)
+
+// BannedError is used when an account that is banned attempts to log in or use an existing session.
+type BannedError struct {
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+// Error implements the error interface.
+func (e *BannedError) Error() string {
+	return "account banned"
+}
+
+// Details returns the ban information that is shown to the banned user.
+func (e *BannedError) Details() Details {
+	return Details{e.Reason, e.ExpiresAt}
+}
+
+// Service provides account ban related services.
+type Service interface {
+	Ban(id int, b NewBan) (Ban, error)
+	Lift(id int) error
+	List(id int) ([]Ban, error)
+	Check(id int) error
+}
+
+type sessionStore interface {
+	RemoveAll(sess session.Session) error
+}
+
+type service struct {
+	sess     sessionStore
+	accounts AccountRepository
+}
+
+// NewService creates a new account ban service.
+func NewService(sess sessionStore, accounts AccountRepository) Service {
+	return &service{sess, accounts}
+}
+
+// Ban bans the account with the given id. If the ban starts immediately, then all sessions of the
+// account are revoked.
+func (s *service) Ban(id int, b NewBan) (Ban, error) {
+	ban, err := s.accounts.Create(id, b)
+	if err != nil {
+		return Ban{}, err
+	}
+
+	if !ban.StartsAt.After(time.Now()) {
+		if err := s.sess.RemoveAll(session.Session{ID: id}); err != nil {
+			return Ban{}, err
+		}
+	}
+	return ban, nil
+}
+
+// Lift lifts the bans of the account with the given id.
+func (s *service) Lift(id int) error {
+	return s.accounts.Lift(id)
+}
+
+// List retrieves all bans of the account with the given id.
+func (s *service) List(id int) ([]Ban, error) {
+	return s.accounts.List(id)
+}
+
+// Check returns a BannedError if the account with the given id is banned.
+func (s *service) Check(id int) error {
+	ban, err := s.accounts.Active(id)
+	if err != nil {
+		if errors.Is(err, ErrNotBanned) {
+			return nil
+		}
+		return err
+	}
+
+	return &BannedError{ban.Reason, ban.ExpiresAt}
+}
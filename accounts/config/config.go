@@ -7,7 +7,9 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-// Server represents http server configuration options.
+// Server represents http server configuration options. The client ip address of a request is only
+// taken from the X-Real-IP header when the request comes from one of the trusted proxy networks,
+// which are given in CIDR notation.
 type Server struct {
 	Port                  int           `yaml:"port"`
 	ReadTimeoutSecs       time.Duration `yaml:"read_timeout_secs"`
@@ -15,6 +17,7 @@ type Server struct {
 	IdleTimeoutSecs       time.Duration `yaml:"idle_timeout_secs"`
 	WriteTimeoutSecs      time.Duration `yaml:"write_timeout_secs"`
 	ShutdownGraceSecs     time.Duration `yaml:"shutdown_grace_secs"`
+	TrustedProxies        []string      `yaml:"trusted_proxies"`
 }
 
 // Database represents postgres database configuration options.
@@ -25,30 +28,254 @@ type Database struct {
 	ConnMaxLifetimeSecs time.Duration `yaml:"conn_max_lifetime_secs"`
 }
 
-// Sessions represents session store configuration options.
+// Sessions represents session store configuration options. Access tokens expire after the access
+// expiry, while sessions last for the session expiry and are extended each time they are refreshed.
 type Sessions struct {
-	Redis             string        `yaml:"redis"`
-	SessionExpiryMins time.Duration `yaml:"session_expiry_mins"`
-	UserExpiryMins    time.Duration `yaml:"user_expiry_mins"`
+	Redis               string        `yaml:"redis"`
+	AccessExpiryMins    time.Duration `yaml:"access_expiry_mins"`
+	SessionExpiryMins   time.Duration `yaml:"session_expiry_mins"`
+	UserExpiryMins      time.Duration `yaml:"user_expiry_mins"`
+	ChallengeExpiryMins time.Duration `yaml:"challenge_expiry_mins"`
+}
+
+// Mail represents outgoing email configuration options. The driver selects how emails are delivered
+// and is either "smtp" or "outbox". The outbox driver writes emails to files in the outbox directory
+// instead of sending them.
+type Mail struct {
+	Driver       string `yaml:"driver"`
+	From         string `yaml:"from"`
+	OutboxDir    string `yaml:"outbox_dir"`
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	LinkBaseURL  string `yaml:"link_base_url"`
+}
+
+// PasswordHashing represents password hashing configuration options. New passwords are hashed with
+// the selected algorithm, which is either "bcrypt" or "argon2id". Existing passwords that were hashed
+// with a different algorithm or different parameters are rehashed on the next successful login.
+type PasswordHashing struct {
+	Algorithm         string `yaml:"algorithm"`
+	BcryptCost        int    `yaml:"bcrypt_cost"`
+	Argon2MemoryKiB   uint32 `yaml:"argon2_memory_kib"`
+	Argon2Iterations  uint32 `yaml:"argon2_iterations"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism"`
+}
+
+// PasswordReset represents password reset configuration options.
+type PasswordReset struct {
+	TokenExpiryMins time.Duration `yaml:"token_expiry_mins"`
+}
+
+// TwoFactor represents two-factor authentication configuration options. The issuer is displayed
+// alongside the account email in authenticator apps.
+type TwoFactor struct {
+	Issuer string `yaml:"issuer"`
+}
+
+// LoginThrottle represents failed login throttling configuration options. Failed attempts are
+// counted separately per account email and per client ip address, and failed second factors are
+// counted per account. Once the number of failures within the window reaches a backoff threshold,
+// further attempts are delayed exponentially up to the maximum delay. Once the number of failures
+// reaches a lockout threshold, attempts are rejected for the full lockout duration.
+type LoginThrottle struct {
+	WindowMins               time.Duration `yaml:"window_mins"`
+	BaseDelaySecs            time.Duration `yaml:"base_delay_secs"`
+	MaxDelaySecs             time.Duration `yaml:"max_delay_secs"`
+	LockoutMins              time.Duration `yaml:"lockout_mins"`
+	EmailBackoffAfter        int           `yaml:"email_backoff_after"`
+	EmailLockoutAfter        int           `yaml:"email_lockout_after"`
+	IPBackoffAfter           int           `yaml:"ip_backoff_after"`
+	IPLockoutAfter           int           `yaml:"ip_lockout_after"`
+	SecondFactorBackoffAfter int           `yaml:"second_factor_backoff_after"`
+	SecondFactorLockoutAfter int           `yaml:"second_factor_lockout_after"`
+}
+
+// RateLimit represents the maximum number of requests allowed to a route within a window.
+type RateLimit struct {
+	Requests   int           `yaml:"requests"`
+	WindowSecs time.Duration `yaml:"window_secs"`
+}
+
+// RateLimits represents request rate limiting configuration options. The backend is either "redis"
+// or "memory". Routes are rate limited by name, and routes that are not listed are not rate
+// limited.
+type RateLimits struct {
+	Backend string               `yaml:"backend"`
+	Redis   string               `yaml:"redis"`
+	Routes  map[string]RateLimit `yaml:"routes"`
+}
+
+// Enumeration represents account enumeration protection configuration options. When protection is
+// enabled, login response timing does not depend on whether the email is registered, and
+// registration responds the same way whether or not the email is already in use.
+type Enumeration struct {
+	Protect bool `yaml:"protect"`
+}
+
+// Registration represents account registration configuration options. When invites are required,
+// accounts can only be registered, and guest accounts can only be upgraded, with a valid invite code,
+// such as during a closed beta.
+type Registration struct {
+	RequireInvite bool `yaml:"require_invite"`
+}
+
+// BotEndpoint represents the bot verification options of an endpoint. If after is zero, then every
+// request to the endpoint must include a valid captcha response. Otherwise, captcha responses are
+// only required once a client ip address has made more than after requests within the window.
+type BotEndpoint struct {
+	Enabled    bool          `yaml:"enabled"`
+	After      int           `yaml:"after"`
+	WindowSecs time.Duration `yaml:"window_secs"`
+}
+
+// BotProtection represents bot verification configuration options. The driver selects how captcha
+// responses are verified and is either "hcaptcha", "turnstile" or "stub". The stub driver accepts
+// only the stub response without contacting a provider. If no verify url is set, then the default
+// url of the provider is used. Endpoints are named "register", "login" and "authenticate", and
+// endpoints that are not listed do not require verification.
+type BotProtection struct {
+	Driver       string                 `yaml:"driver"`
+	Secret       string                 `yaml:"secret"`
+	VerifyURL    string                 `yaml:"verify_url"`
+	TimeoutSecs  time.Duration          `yaml:"timeout_secs"`
+	StubResponse string                 `yaml:"stub_response"`
+	Endpoints    map[string]BotEndpoint `yaml:"endpoints"`
+}
+
+// Admin represents configuration options for internal admin routes. Admin routes are authorized by
+// the roles of the account that makes the request. The verified accounts with the bootstrap emails
+// are granted the admin role at startup so that roles can be assigned to other accounts. Bootstrap
+// emails that do not belong to a verified account yet are skipped.
+type Admin struct {
+	BootstrapEmails []string `yaml:"bootstrap_emails"`
+}
+
+// Guests represents guest account configuration options. Guest accounts that have not been upgraded
+// are deleted once they have not logged in for the expiry period. Expired guest accounts are purged
+// every purge interval.
+type Guests struct {
+	ExpiryDays        time.Duration `yaml:"expiry_days"`
+	PurgeIntervalMins time.Duration `yaml:"purge_interval_mins"`
+}
+
+// DisplayNames represents display name configuration options. Display names must be between the
+// minimum and maximum length in characters and fully match the pattern. Names that equal a reserved
+// word or contain a word from the blocked words file are rejected. Display names can be changed once
+// per cooldown period.
+type DisplayNames struct {
+	MinLength        int           `yaml:"min_length"`
+	MaxLength        int           `yaml:"max_length"`
+	Pattern          string        `yaml:"pattern"`
+	CooldownDays     time.Duration `yaml:"cooldown_days"`
+	ReservedWords    []string      `yaml:"reserved_words"`
+	BlockedWordsFile string        `yaml:"blocked_words_file"`
+}
+
+// AccountDeletion represents account deletion configuration options. Accounts are purged once the
+// grace period after the deletion request has passed, and can be restored by logging in before
+// then. The purge mode is either "delete", which removes accounts entirely, or "anonymize", which
+// keeps the account id but removes all personal data.
+type AccountDeletion struct {
+	GraceDays         time.Duration `yaml:"grace_days"`
+	PurgeIntervalMins time.Duration `yaml:"purge_interval_mins"`
+	PurgeMode         string        `yaml:"purge_mode"`
+}
+
+// DataExports represents personal data export configuration options. Pending exports are generated
+// every process interval, and completed exports can be downloaded for the retention period. Exports
+// that are still running after the claim timeout are generated again.
+type DataExports struct {
+	ProcessIntervalSecs time.Duration `yaml:"process_interval_secs"`
+	RetentionHours      time.Duration `yaml:"retention_hours"`
+	ClaimTimeoutMins    time.Duration `yaml:"claim_timeout_mins"`
+}
+
+// LoginHistory represents login history configuration options. Links in new device notifications
+// can revoke the new session for the revoke period. Logins are kept for the retention period and
+// older logins are purged every purge interval.
+type LoginHistory struct {
+	RevokeHours       time.Duration `yaml:"revoke_hours"`
+	RetentionDays     time.Duration `yaml:"retention_days"`
+	PurgeIntervalMins time.Duration `yaml:"purge_interval_mins"`
+}
+
+// EmailChange represents email change configuration options. Confirmation links that are sent to
+// the new email address expire after the confirm period, and revert links that are sent to the old
+// email address expire after the revert period.
+type EmailChange struct {
+	ConfirmHours time.Duration `yaml:"confirm_hours"`
+	RevertHours  time.Duration `yaml:"revert_hours"`
+}
+
+// Introspection represents configuration options for the token introspection route. The route is
+// only served when enabled, and internal services authenticate with one of the service keys.
+type Introspection struct {
+	Enabled     bool     `yaml:"enabled"`
+	ServiceKeys []string `yaml:"service_keys"`
+}
+
+// GameTokenKey represents a game token signing key. The private key file contains a PKCS #8 encoded
+// Ed25519 or P-256 private key in PEM format.
+type GameTokenKey struct {
+	ID             string `yaml:"id"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+}
+
+// GameTokens represents configuration options for the signed access tokens that are issued to game
+// servers. Tokens are signed with the active key, and all configured keys are published so that
+// keys can be rotated without invalidating tokens that have already been issued. If no keys are
+// configured, the server refuses to start unless ephemeral keys are allowed, in which case a key is
+// generated on startup. Ephemeral keys are meant for local development only.
+type GameTokens struct {
+	Issuer         string         `yaml:"issuer"`
+	Audience       string         `yaml:"audience"`
+	ExpirySecs     time.Duration  `yaml:"expiry_secs"`
+	ActiveKey      string         `yaml:"active_key"`
+	Keys           []GameTokenKey `yaml:"keys"`
+	AllowEphemeral bool           `yaml:"allow_ephemeral"`
 }
 
 // Config represents the server configuration options.
 type Config struct {
-	Server   Server   `yaml:"server"`
-	Database Database `yaml:"database"`
-	Sessions Sessions `yaml:"sessions"`
+	Server          Server          `yaml:"server"`
+	Database        Database        `yaml:"database"`
+	Sessions        Sessions        `yaml:"sessions"`
+	Mail            Mail            `yaml:"mail"`
+	PasswordHashing PasswordHashing `yaml:"password_hashing"`
+	PasswordReset   PasswordReset   `yaml:"password_reset"`
+	TwoFactor       TwoFactor       `yaml:"two_factor"`
+	LoginThrottle   LoginThrottle   `yaml:"login_throttle"`
+	RateLimits      RateLimits      `yaml:"rate_limits"`
+	Enumeration     Enumeration     `yaml:"enumeration"`
+	Admin           Admin           `yaml:"admin"`
+	GameTokens      GameTokens      `yaml:"game_tokens"`
+	Introspection   Introspection   `yaml:"introspection"`
+	Guests          Guests          `yaml:"guests"`
+	DisplayNames    DisplayNames    `yaml:"display_names"`
+	AccountDeletion AccountDeletion `yaml:"account_deletion"`
+	DataExports     DataExports     `yaml:"data_exports"`
+	LoginHistory    LoginHistory    `yaml:"login_history"`
+	EmailChange     EmailChange     `yaml:"email_change"`
+	Registration    Registration    `yaml:"registration"`
+	BotProtection   BotProtection   `yaml:"bot_protection"`
 }
 
-// Load attempts to load the app configuration from the file located at the provided path.
-func Load(path string) (*Config, error) {
-	bytes, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
+// Load attempts to load the app configuration from the files located at the provided paths. Each
+// file is applied on top of the ones before it, so later files only need to contain the options
+// that they override. Lists are replaced as a whole rather than merged.
+func Load(paths ...string) (*Config, error) {
 	var cfg Config
-	if err := yaml.Unmarshal(bytes, &cfg); err != nil {
-		return nil, err
+	for _, path := range paths {
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.Unmarshal(bytes, &cfg); err != nil {
+			return nil, err
+		}
 	}
 	return &cfg, nil
 }
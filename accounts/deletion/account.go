@@ -0,0 +1,26 @@
+package deletion
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Request represents a request to delete an account. The current password must be supplied to
+// confirm the deletion.
+type Request struct {
+	Password string `json:"password"`
+}
+
+// Validate validates deletion request data.
+func (r Request) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Password, validation.Required),
+	)
+}
+
+// Scheduled represents a pending account deletion. The account is purged at the scheduled time
+// unless the deletion is cancelled by logging in before then.
+type Scheduled struct {
+	ScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
@@ -0,0 +1,155 @@
+package deletion
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"untitled_game/core/token"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrGuestAccount is used when attempting to delete a guest account. Guest accounts have no
+// password to confirm the deletion with and are purged automatically once they expire.
+var ErrGuestAccount = errors.New("guest account")
+
+// Account represents the account info that is needed to schedule the deletion of an account.
+type Account struct {
+	Email    sql.NullString `db:"email"`
+	Password sql.NullString `db:"password"`
+}
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Get(id int) (Account, error)
+	Schedule(id int, at time.Time) error
+	Purge(anonymize bool) ([]int, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Get retrieves the email and hashed password of the account with the given id.
+func (r *accountRepository) Get(id int) (Account, error) {
+	const q = `SELECT email, password FROM accounts WHERE id = $1 AND deleted_at IS NULL`
+
+	var account Account
+	if err := r.db.Get(&account, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, ErrAccountNotFound
+		}
+		return account, err
+	}
+	if !account.Password.Valid {
+		return account, ErrGuestAccount
+	}
+	return account, nil
+}
+
+// Schedule marks the account with the given id for deletion at the given time.
+func (r *accountRepository) Schedule(id int, at time.Time) error {
+	const q = `UPDATE accounts SET deletion_scheduled_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	res, err := r.db.Exec(q, id, at)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// purgedAccount represents an account that is due to be purged along with its canonical email.
+type purgedAccount struct {
+	ID         int            `db:"id"`
+	Normalized sql.NullString `db:"normalized_email"`
+}
+
+// Purge removes the accounts whose scheduled deletion time has passed and returns the ids of the
+// purged accounts. Accounts are either deleted along with all of their data, or anonymized so that
+// the account id stays valid for game data that references it while everything that identifies the
+// player is removed.
+//
+// Anonymized accounts keep their bans, so that the moderation history of an id that game data still
+// references is not lost. Bans only hold the reason and the issuer, not the player's details. Audit
+// events are kept in both modes, since the audit log is append-only so that it can be trusted when
+// investigating incidents. The ip address, user agent and payload of the events of purged accounts
+// are scrubbed, which is the only change the audit log allows. This includes failed logins for the
+// account email, which are tied to the email by its hash rather than to the account.
+func (r *accountRepository) Purge(anonymize bool) ([]int, error) {
+	const (
+		qDue       = `SELECT id, normalized_email FROM accounts WHERE deletion_scheduled_at <= now() ORDER BY id FOR UPDATE`
+		qScrub     = `UPDATE audit_events SET ip = '', user_agent = '', payload = '{}' WHERE account_id = ANY($1) OR actor_id = ANY($1) OR payload->>'email_hash' = ANY($2)`
+		qDelete    = `DELETE FROM accounts WHERE id = ANY($1)`
+		qAnonymize = `UPDATE accounts SET email = NULL, normalized_email = NULL, password = NULL, display_name = NULL, display_name_changed_at = NULL, guest_device_hash = NULL, verification_token = NULL, verification_token_expires_at = NULL, last_login_at = NULL, deletion_scheduled_at = NULL, deleted_at = now() WHERE id = ANY($1)`
+	)
+
+	// anonymizeQueries remove the data of anonymized accounts that is stored outside of the accounts
+	// table.
+	anonymizeQueries := []string{
+		qAnonymize,
+		`DELETE FROM two_factor WHERE account_id = ANY($1)`,
+		`DELETE FROM recovery_codes WHERE account_id = ANY($1)`,
+		`DELETE FROM password_resets WHERE account_id = ANY($1)`,
+		`DELETE FROM display_name_history WHERE account_id = ANY($1)`,
+		`DELETE FROM data_exports WHERE account_id = ANY($1)`,
+		`DELETE FROM login_history WHERE account_id = ANY($1)`,
+		`DELETE FROM email_changes WHERE account_id = ANY($1)`,
+		`DELETE FROM invite_redemptions WHERE account_id = ANY($1)`,
+		`DELETE FROM account_roles WHERE account_id = ANY($1)`,
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var due []purgedAccount
+	if err := tx.Select(&due, qDue); err != nil {
+		return nil, err
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int, len(due))
+	var hashes []string
+	for i, a := range due {
+		ids[i] = a.ID
+		if a.Normalized.Valid {
+			hashes = append(hashes, token.Hash(a.Normalized.String))
+		}
+	}
+
+	if _, err := tx.Exec(qScrub, pq.Array(ids), pq.Array(hashes)); err != nil {
+		return nil, err
+	}
+
+	queries := []string{qDelete}
+	if anonymize {
+		queries = anonymizeQueries
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q, pq.Array(ids)); err != nil {
+			return nil, err
+		}
+	}
+	return ids, tx.Commit()
+}
@@ -0,0 +1,139 @@
+package deletion
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/session"
+	"untitled_game/core/hasher"
+	"untitled_game/core/mail"
+)
+
+// scheduledEmail is the body of the email that is sent when an account is scheduled for deletion.
+const scheduledEmail = `Your Untitled Game account is scheduled to be deleted on %s.
+
+All of your sessions have been logged out. If you change your mind, log in to your account before
+then and the deletion will be cancelled.
+
+If you did not request this, log in to your account right away and change your password.
+`
+
+// ErrIncorrectPassword is used when the supplied password does not match the password of the
+// account.
+var ErrIncorrectPassword = errors.New("incorrect password")
+
+// Service provides account deletion related services.
+type Service interface {
+	Delete(sess session.Session, req Request, meta session.Metadata) (Scheduled, error)
+	Purge() (int, error)
+}
+
+type sessionStore interface {
+	RemoveAll(sess session.Session) error
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+// ServiceConfig represents configuration options for an account deletion service. Accounts are
+// purged once the grace period after the deletion request has passed. When anonymize is set, purged
+// accounts are anonymized instead of deleted.
+type ServiceConfig struct {
+	GracePeriod time.Duration
+	Anonymize   bool
+}
+
+type service struct {
+	sess        sessionStore
+	accounts    AccountRepository
+	hasher      hasher.Hasher
+	mailer      mail.Mailer
+	audit       auditor
+	gracePeriod time.Duration
+	anonymize   bool
+}
+
+// NewService creates a new account deletion service. The mailer should deliver messages in the
+// background, so that a failure to send the notice does not fail a deletion that is already
+// scheduled.
+func NewService(sess sessionStore, accounts AccountRepository, hasher hasher.Hasher, mailer mail.Mailer, audit auditor, cfg ServiceConfig) Service {
+	return &service{
+		sess:        sess,
+		accounts:    accounts,
+		hasher:      hasher,
+		mailer:      mailer,
+		audit:       audit,
+		gracePeriod: cfg.GracePeriod,
+		anonymize:   cfg.Anonymize,
+	}
+}
+
+// Delete schedules the account that owns the session for deletion after confirming the password of
+// the account. All sessions for the account are revoked, and the account owner is notified of the
+// scheduled deletion by email. Logging in before the scheduled time cancels the deletion.
+func (s *service) Delete(sess session.Session, req Request, meta session.Metadata) (Scheduled, error) {
+	account, err := s.accounts.Get(sess.ID)
+	if err != nil {
+		return Scheduled{}, err
+	}
+
+	if err := s.hasher.Compare(account.Password.String, req.Password); err != nil {
+		if errors.Is(err, hasher.ErrMismatch) {
+			return Scheduled{}, ErrIncorrectPassword
+		}
+		return Scheduled{}, err
+	}
+
+	at := time.Now().Add(s.gracePeriod).UTC()
+	if err := s.accounts.Schedule(sess.ID, at); err != nil {
+		return Scheduled{}, err
+	}
+
+	if err := s.sess.RemoveAll(sess); err != nil {
+		return Scheduled{}, err
+	}
+
+	err = s.mailer.Send(mail.Message{
+		To:      account.Email.String,
+		Subject: "Your account is scheduled for deletion",
+		Body:    fmt.Sprintf(scheduledEmail, at.Format("January 2, 2006 15:04 MST")),
+	})
+	if err != nil {
+		return Scheduled{}, err
+	}
+
+	scheduled := Scheduled{at}
+	s.record(audit.EventDeletionScheduled, sess.ID, meta, scheduled)
+	return scheduled, nil
+}
+
+// Purge removes accounts whose scheduled deletion time has passed along with any sessions they
+// still have. The number of purged accounts is returned.
+func (s *service) Purge() (int, error) {
+	ids, err := s.accounts.Purge(s.anonymize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.sess.RemoveAll(session.Session{ID: id}); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// record records an audit event that was caused by and affects the account with the given id.
+func (s *service) record(eventType string, id int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
@@ -0,0 +1,39 @@
+package displayname
+
+import (
+	"database/sql"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Account represents the display name of an account along with the time it was last changed.
+type Account struct {
+	DisplayName sql.NullString `db:"display_name"`
+	ChangedAt   sql.NullTime   `db:"display_name_changed_at"`
+}
+
+// Change represents a request to change the display name of an account.
+type Change struct {
+	DisplayName string `json:"display_name"`
+}
+
+// Validate validates display name change data. The configured length, charset, and word list rules
+// are applied by the display name service.
+func (c Change) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.DisplayName, validation.Required),
+	)
+}
+
+// DisplayName represents the current display name of an account.
+type DisplayName struct {
+	DisplayName  string    `json:"display_name"`
+	NextChangeAt time.Time `json:"next_change_at"`
+}
+
+// HistoryEntry represents a display name that was used by an account, starting at the given time.
+type HistoryEntry struct {
+	DisplayName string    `json:"display_name" db:"display_name"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,99 @@
+package displayname
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"untitled_game/core/postgres"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrNameTaken is used when a display name is already in use by another account. Display names are
+// compared case-insensitively.
+var ErrNameTaken = errors.New("display name taken")
+
+// ErrChangedRecently is used when a display name cannot be changed because the previous change was
+// made too recently.
+var ErrChangedRecently = errors.New("display name changed recently")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Get(id int) (Account, error)
+	Set(id int, name string, changedBefore time.Time) error
+	History(id int) ([]HistoryEntry, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Get retrieves the display name of the account with the given id.
+func (r *accountRepository) Get(id int) (Account, error) {
+	const q = `SELECT display_name, display_name_changed_at FROM accounts WHERE id = $1`
+
+	var account Account
+	if err := r.db.Get(&account, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, ErrAccountNotFound
+		}
+		return account, err
+	}
+	return account, nil
+}
+
+// Set changes the display name of the account with the given id and records the new name in the
+// display name history. The name is only changed if it has not been changed since the given time.
+func (r *accountRepository) Set(id int, name string, changedBefore time.Time) error {
+	const (
+		qSet     = `UPDATE accounts SET display_name = $2, display_name_changed_at = now() WHERE id = $1 AND (display_name_changed_at IS NULL OR display_name_changed_at < $3)`
+		qHistory = `INSERT INTO display_name_history (account_id, display_name) VALUES ($1, $2)`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(qSet, id, name, changedBefore)
+	if err != nil {
+		if postgres.IsUniqueViolationError(err) {
+			return ErrNameTaken
+		}
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrChangedRecently
+	}
+
+	if _, err := tx.Exec(qHistory, id, name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// History retrieves the display names that have been used by the account with the given id, most
+// recent first.
+func (r *accountRepository) History(id int) ([]HistoryEntry, error) {
+	const q = `SELECT display_name, created_at FROM display_name_history WHERE account_id = $1 ORDER BY created_at DESC, id DESC`
+
+	history := []HistoryEntry{}
+	if err := r.db.Select(&history, q, id); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
@@ -0,0 +1,94 @@
+package displayname
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// leet maps characters that are commonly substituted for letters to the letters they stand for, so
+// that blocked words cannot be sneaked past the filter.
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+}
+
+// Filter rejects display names that match reserved words or contain blocked words. Names are
+// normalized before matching, so case, separators, and common character substitutions are ignored.
+type Filter struct {
+	reserved map[string]struct{}
+	blocked  []string
+}
+
+// NewFilter creates a new display name filter. Reserved words are rejected when a name matches them
+// exactly, while blocked words are rejected anywhere within a name.
+func NewFilter(reserved []string, blocked []string) *Filter {
+	f := &Filter{reserved: make(map[string]struct{}, len(reserved))}
+	for _, word := range reserved {
+		if n := normalize(word); n != "" {
+			f.reserved[n] = struct{}{}
+		}
+	}
+	for _, word := range blocked {
+		if n := normalize(word); n != "" {
+			f.blocked = append(f.blocked, n)
+		}
+	}
+	return f
+}
+
+// ReadWordlist reads a word list with one word per line. Empty lines and lines starting with # are
+// ignored.
+func ReadWordlist(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// Allowed reports whether a display name passes the filter.
+func (f *Filter) Allowed(name string) bool {
+	n := normalize(name)
+	if _, ok := f.reserved[n]; ok {
+		return false
+	}
+	for _, word := range f.blocked {
+		if strings.Contains(n, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize lowercases a name, replaces common character substitutions, and removes all characters
+// that are not letters or digits.
+func normalize(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
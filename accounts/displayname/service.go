@@ -0,0 +1,111 @@
+package displayname
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ErrNameNotAllowed is used when a display name matches a reserved word or contains a blocked word.
+var ErrNameNotAllowed = errors.New("display name not allowed")
+
+// Service provides display name related services.
+type Service interface {
+	Get(id int) (DisplayName, error)
+	Change(id int, c Change) (DisplayName, error)
+	History(id int) ([]HistoryEntry, error)
+}
+
+// ServiceConfig represents configuration options for a display name service. Display names must be
+// between the minimum and maximum length in characters and must match the pattern in full. Once a
+// display name has been changed, it cannot be changed again until the cooldown has passed. Setting
+// the first display name of an account is not subject to the cooldown.
+type ServiceConfig struct {
+	MinLength int
+	MaxLength int
+	Pattern   *regexp.Regexp
+	Cooldown  time.Duration
+	Filter    *Filter
+}
+
+type service struct {
+	accounts  AccountRepository
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+	cooldown  time.Duration
+	filter    *Filter
+}
+
+// NewService creates a new display name service.
+func NewService(accounts AccountRepository, cfg ServiceConfig) Service {
+	return &service{
+		accounts:  accounts,
+		minLength: cfg.MinLength,
+		maxLength: cfg.MaxLength,
+		pattern:   cfg.Pattern,
+		cooldown:  cfg.Cooldown,
+		filter:    cfg.Filter,
+	}
+}
+
+// Get retrieves the display name of the account with the given id along with the earliest time it
+// can be changed.
+func (s *service) Get(id int) (DisplayName, error) {
+	account, err := s.accounts.Get(id)
+	if err != nil {
+		return DisplayName{}, err
+	}
+	return s.displayName(account), nil
+}
+
+// Change changes the display name of the account with the given id. If the display name does not
+// satisfy the length and charset rules, then validation errors are returned.
+func (s *service) Change(id int, c Change) (DisplayName, error) {
+	if err := s.validate(c.DisplayName); err != nil {
+		return DisplayName{}, err
+	}
+	if s.filter != nil && !s.filter.Allowed(c.DisplayName) {
+		return DisplayName{}, ErrNameNotAllowed
+	}
+
+	account, err := s.accounts.Get(id)
+	if err != nil {
+		return DisplayName{}, err
+	}
+	if account.DisplayName.Valid && account.DisplayName.String == c.DisplayName {
+		return s.displayName(account), nil
+	}
+
+	if err := s.accounts.Set(id, c.DisplayName, time.Now().Add(-s.cooldown)); err != nil {
+		return DisplayName{}, err
+	}
+	return DisplayName{c.DisplayName, time.Now().Add(s.cooldown).UTC()}, nil
+}
+
+// History retrieves the display names that have been used by the account with the given id, most
+// recent first.
+func (s *service) History(id int) ([]HistoryEntry, error) {
+	return s.accounts.History(id)
+}
+
+// validate checks a display name against the length and charset rules.
+func (s *service) validate(name string) error {
+	return validation.Errors{
+		"display_name": validation.Validate(name,
+			validation.RuneLength(s.minLength, s.maxLength),
+			validation.Match(s.pattern),
+		),
+	}.Filter()
+}
+
+// displayName creates the display name response for an account.
+func (s *service) displayName(account Account) DisplayName {
+	var next time.Time
+	if account.ChangedAt.Valid {
+		next = account.ChangedAt.Time.Add(s.cooldown).UTC()
+	}
+	return DisplayName{account.DisplayName.String, next}
+}
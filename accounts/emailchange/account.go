@@ -0,0 +1,64 @@
+package emailchange
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+// Request represents a request to change the email address of the current account. The password is
+// required to confirm that the request was made by the account owner.
+type Request struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Validate validates email change request data.
+func (r Request) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Email, validation.Required, is.Email),
+		validation.Field(&r.Password, validation.Required),
+	)
+}
+
+// Token represents a confirmation token that is sent to the new email address, or a revert token
+// that is sent to the old email address.
+type Token struct {
+	Token string `json:"token"`
+}
+
+// Validate validates token data.
+func (t Token) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Token, validation.Required),
+	)
+}
+
+// Account represents the account info that is used to authorize an email change.
+type Account struct {
+	Email    *string `db:"email"`
+	Password *string `db:"password"`
+}
+
+// Entry represents an email change in the email change history of an account.
+type Entry struct {
+	OldEmail    string     `json:"old_email" db:"old_email"`
+	NewEmail    string     `json:"new_email" db:"new_email"`
+	ConfirmedAt *time.Time `json:"confirmed_at" db:"confirmed_at"`
+	RevertedAt  *time.Time `json:"reverted_at" db:"reverted_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Change represents a pending or completed change of an account email address.
+type Change struct {
+	ID               int       `db:"id"`
+	AccountID        int       `db:"account_id"`
+	OldEmail         string    `db:"old_email"`
+	NewEmail         string    `db:"new_email"`
+	ConfirmTokenHash string    `db:"confirm_token_hash"`
+	RevertTokenHash  string    `db:"revert_token_hash"`
+	ConfirmExpiresAt time.Time `db:"confirm_expires_at"`
+	RevertExpiresAt  time.Time `db:"revert_expires_at"`
+	Confirmed        bool      `db:"confirmed"`
+}
@@ -0,0 +1,218 @@
+package emailchange
+
+import (
+	"database/sql"
+	"errors"
+	"untitled_game/core/normalize"
+	"untitled_game/core/postgres"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrGuestAccount is used when a guest account attempts to change its email address. Guest accounts
+// have no email address until they are upgraded.
+var ErrGuestAccount = errors.New("guest account")
+
+// ErrEmailTaken is used when the new email address is already in use by another account.
+var ErrEmailTaken = errors.New("email address already in use")
+
+// ErrInvalidToken is used when a confirmation or revert token does not exist, has expired, or has
+// already been used.
+var ErrInvalidToken = errors.New("invalid email change token")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Get(id int) (Account, error)
+	Create(c Change) error
+	Confirm(confirmTokenHash string) (Change, error)
+	Revert(revertTokenHash string) (Change, error)
+	List(id int) ([]Entry, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Get retrieves the email address and hashed password of the account with the given id.
+func (r *accountRepository) Get(id int) (Account, error) {
+	const q = `SELECT email, password FROM accounts WHERE id = $1`
+
+	var account Account
+	if err := r.db.Get(&account, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, ErrAccountNotFound
+		}
+		return account, err
+	}
+	if account.Email == nil || account.Password == nil {
+		return account, ErrGuestAccount
+	}
+	return account, nil
+}
+
+// Create stores a new pending email change, replacing any pending change of the same account that
+// has not been confirmed.
+func (r *accountRepository) Create(c Change) error {
+	const (
+		qDelete = `DELETE FROM email_changes WHERE account_id = $1 AND confirmed_at IS NULL`
+		qInsert = `INSERT INTO email_changes (account_id, old_email, new_email, confirm_token_hash, revert_token_hash, confirm_expires_at, revert_expires_at)
+			VALUES (:account_id, :old_email, :new_email, :confirm_token_hash, :revert_token_hash, :confirm_expires_at, :revert_expires_at)`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(qDelete, c.AccountID); err != nil {
+		return err
+	}
+	if _, err := tx.NamedExec(qInsert, c); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Confirm swaps the email address of the account for the new email address of the pending change
+// with the given confirmation token hash. The new email address is considered verified, since the
+// token was delivered to it. The swap only happens if the account still has the old email address,
+// and the unique email constraint guarantees that no two accounts share an email address.
+func (r *accountRepository) Confirm(confirmTokenHash string) (Change, error) {
+	const q = `UPDATE email_changes SET confirmed_at = now()
+		WHERE confirm_token_hash = $1 AND confirmed_at IS NULL AND reverted_at IS NULL AND confirm_expires_at > now()
+		RETURNING account_id, old_email, new_email, true AS confirmed`
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return Change{}, err
+	}
+	defer tx.Rollback()
+
+	var c Change
+	if err := tx.Get(&c, q, confirmTokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c, ErrInvalidToken
+		}
+		return c, err
+	}
+
+	if err := swap(tx, c.AccountID, c.OldEmail, c.NewEmail); err != nil {
+		return Change{}, err
+	}
+	return c, tx.Commit()
+}
+
+// Revert cancels the email change with the given revert token hash along with every later change of
+// the same account, whether pending or confirmed. If any of the cancelled changes has been
+// confirmed, then the account is given back the old email address of the reverted change regardless
+// of its current email address, so that a revert link cannot be defeated by changing the email
+// address again.
+func (r *accountRepository) Revert(revertTokenHash string) (Change, error) {
+	const (
+		q = `UPDATE email_changes SET reverted_at = now()
+			WHERE revert_token_hash = $1 AND reverted_at IS NULL AND revert_expires_at > now()
+			RETURNING id, account_id, old_email, new_email, confirmed_at IS NOT NULL AS confirmed`
+		qLater = `UPDATE email_changes SET reverted_at = now()
+			WHERE account_id = $1 AND id > $2 AND reverted_at IS NULL
+			RETURNING confirmed_at IS NOT NULL`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return Change{}, err
+	}
+	defer tx.Rollback()
+
+	var c Change
+	if err := tx.Get(&c, q, revertTokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c, ErrInvalidToken
+		}
+		return c, err
+	}
+
+	var later []bool
+	if err := tx.Select(&later, qLater, c.AccountID, c.ID); err != nil {
+		return Change{}, err
+	}
+
+	restore := c.Confirmed
+	for _, confirmed := range later {
+		restore = restore || confirmed
+	}
+	if restore {
+		if err := restoreEmail(tx, c.AccountID, c.OldEmail); err != nil {
+			return Change{}, err
+		}
+	}
+	return c, tx.Commit()
+}
+
+// restoreEmail gives an account back an email address within a transaction, whatever its current
+// email address is. The restored email address is marked as verified.
+func restoreEmail(tx *sqlx.Tx, id int, email string) error {
+	const q = `UPDATE accounts SET email = $2, normalized_email = $3, verified_at = now(), verification_token = NULL, verification_token_expires_at = NULL
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	res, err := tx.Exec(q, id, email, normalize.Email(email))
+	if err != nil {
+		if postgres.IsUniqueViolationError(err) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// List retrieves the email changes of the account with the given id, oldest first.
+func (r *accountRepository) List(id int) ([]Entry, error) {
+	const q = `SELECT old_email, new_email, confirmed_at, reverted_at, created_at FROM email_changes WHERE account_id = $1 ORDER BY created_at, id`
+
+	entries := []Entry{}
+	if err := r.db.Select(&entries, q, id); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// swap replaces the email address of an account within a transaction, as long as the account still
+// has the email address that is being replaced. The replacement email address is marked as verified,
+// and the canonical email of the account is updated to match it.
+func swap(tx *sqlx.Tx, id int, from string, to string) error {
+	const q = `UPDATE accounts SET email = $3, normalized_email = $4, verified_at = now(), verification_token = NULL, verification_token_expires_at = NULL
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL`
+
+	res, err := tx.Exec(q, id, from, to, normalize.Email(to))
+	if err != nil {
+		if postgres.IsUniqueViolationError(err) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
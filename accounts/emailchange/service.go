@@ -0,0 +1,219 @@
+package emailchange
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/session"
+	"untitled_game/core/hasher"
+	"untitled_game/core/mail"
+	"untitled_game/core/token"
+)
+
+// confirmEmail is the body of the email that is sent to the new email address when an email change
+// is requested.
+const confirmEmail = `A request was made to change the email address of an Untitled Game account to this address.
+
+To confirm the change, visit the link below:
+
+%s
+
+This link expires in %d hours. If you did not request this change, you can safely ignore this
+email.
+`
+
+// noticeEmail is the body of the email that is sent to the old email address when an email change
+// is requested.
+const noticeEmail = `A request was made to change the email address of your Untitled Game account to %s.
+
+The change takes effect once it is confirmed from the new address. If you did not request this
+change, visit the link below to cancel it, or to restore this address if the change has already
+been confirmed. All devices will be signed out of your account.
+
+%s
+
+This link expires in %d hours. We also recommend that you change your password.
+`
+
+// ErrIncorrectPassword is used when the supplied password does not match the password of the
+// account.
+var ErrIncorrectPassword = errors.New("incorrect password")
+
+// ErrSameEmail is used when the new email address is the same as the current email address.
+var ErrSameEmail = errors.New("email address unchanged")
+
+// Service provides email address change related services.
+type Service interface {
+	Request(sess session.Session, r Request, meta session.Metadata) error
+	Confirm(t Token, meta session.Metadata) error
+	Revert(t Token, meta session.Metadata) error
+	History(id int) ([]Entry, error)
+}
+
+type sessionStore interface {
+	RemoveAll(sess session.Session) error
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+// ServiceConfig represents configuration options for an email change service. The confirm url is
+// sent to the new email address and the revert url is sent to the old email address. Revert links
+// remain valid for longer than confirmation links so that a confirmed change can still be undone by
+// the owner of the old email address.
+type ServiceConfig struct {
+	ConfirmURL string
+	RevertURL  string
+	ConfirmTTL time.Duration
+	RevertTTL  time.Duration
+}
+
+type service struct {
+	sess       sessionStore
+	accounts   AccountRepository
+	hasher     hasher.Hasher
+	mailer     mail.Mailer
+	audit      auditor
+	confirmURL string
+	revertURL  string
+	confirmTTL time.Duration
+	revertTTL  time.Duration
+}
+
+// NewService creates a new email change service. The mailer should deliver messages in the
+// background, since the confirmation and notice emails are sent after the change has been stored.
+func NewService(sess sessionStore, accounts AccountRepository, hasher hasher.Hasher, mailer mail.Mailer, audit auditor, cfg ServiceConfig) Service {
+	return &service{
+		sess:       sess,
+		accounts:   accounts,
+		hasher:     hasher,
+		mailer:     mailer,
+		audit:      audit,
+		confirmURL: cfg.ConfirmURL,
+		revertURL:  cfg.RevertURL,
+		confirmTTL: cfg.ConfirmTTL,
+		revertTTL:  cfg.RevertTTL,
+	}
+}
+
+// Request starts an email change for the account that owns the session after confirming its
+// password. A confirmation link is sent to the new email address and a notice with a revert link is
+// sent to the old email address. The email address is not changed until the change is confirmed.
+// Whether the new email address is in use is only revealed once the change is confirmed, so that
+// the request cannot be used to find out which email addresses are registered.
+func (s *service) Request(sess session.Session, r Request, meta session.Metadata) error {
+	account, err := s.accounts.Get(sess.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.hasher.Compare(*account.Password, r.Password); err != nil {
+		if errors.Is(err, hasher.ErrMismatch) {
+			return ErrIncorrectPassword
+		}
+		return err
+	}
+
+	newEmail := strings.ToLower(r.Email)
+	if newEmail == *account.Email {
+		return ErrSameEmail
+	}
+
+	confirmToken, err := token.Generate(32)
+	if err != nil {
+		return err
+	}
+	revertToken, err := token.Generate(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.accounts.Create(Change{
+		AccountID:        sess.ID,
+		OldEmail:         *account.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: token.Hash(confirmToken),
+		RevertTokenHash:  token.Hash(revertToken),
+		ConfirmExpiresAt: now.Add(s.confirmTTL),
+		RevertExpiresAt:  now.Add(s.revertTTL),
+	}); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body:    fmt.Sprintf(confirmEmail, s.confirmURL+"?token="+url.QueryEscape(confirmToken), int(s.confirmTTL.Hours())),
+	}); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(mail.Message{
+		To:      *account.Email,
+		Subject: "Your email address is being changed",
+		Body:    fmt.Sprintf(noticeEmail, newEmail, s.revertURL+"?token="+url.QueryEscape(revertToken), int(s.revertTTL.Hours())),
+	}); err != nil {
+		return err
+	}
+
+	s.record(audit.EventEmailChangeRequested, sess.ID, meta, emailChange{*account.Email, newEmail})
+	return nil
+}
+
+// Confirm completes the email change that the confirmation token was issued for.
+func (s *service) Confirm(t Token, meta session.Metadata) error {
+	c, err := s.accounts.Confirm(token.Hash(t.Token))
+	if err != nil {
+		return err
+	}
+
+	s.record(audit.EventEmailChanged, c.AccountID, meta, emailChange{c.OldEmail, c.NewEmail})
+	return nil
+}
+
+// Revert cancels the email change that the revert token was issued for along with any later email
+// changes, restoring the old email address if any of them has already been confirmed. All sessions
+// of the account are revoked, since an unwanted email change suggests that the account has been
+// compromised.
+func (s *service) Revert(t Token, meta session.Metadata) error {
+	c, err := s.accounts.Revert(token.Hash(t.Token))
+	if err != nil {
+		return err
+	}
+
+	if err := s.sess.RemoveAll(session.Session{ID: c.AccountID}); err != nil {
+		return err
+	}
+
+	s.record(audit.EventEmailChangeReverted, c.AccountID, meta, emailChange{c.NewEmail, c.OldEmail})
+	return nil
+}
+
+// History retrieves the email changes of the account with the given id.
+func (s *service) History(id int) ([]Entry, error) {
+	return s.accounts.List(id)
+}
+
+// emailChange represents the payload of an email change audit event.
+type emailChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// record records an audit event that affects the account with the given id.
+func (s *service) record(eventType string, id int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
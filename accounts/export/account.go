@@ -0,0 +1,64 @@
+package export
+
+import (
+	"database/sql"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Export formats.
+const (
+	FormatJSON = "json"
+	FormatZIP  = "zip"
+)
+
+// Export statuses.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Request represents a request to export the personal data of an account. The format is either
+// "json" or "zip" and defaults to "json".
+type Request struct {
+	Format string `json:"format"`
+}
+
+// Validate validates export request data.
+func (r Request) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Format, validation.In(FormatJSON, FormatZIP)),
+	)
+}
+
+// Export represents a personal data export along with its generation status. Completed exports can
+// be downloaded until they expire.
+type Export struct {
+	ID          string       `json:"id" db:"id"`
+	AccountID   int          `json:"-" db:"account_id"`
+	Format      string       `json:"format" db:"format"`
+	Status      string       `json:"status" db:"status"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	CompletedAt sql.NullTime `json:"-" db:"completed_at"`
+	ExpiresAt   sql.NullTime `json:"-" db:"expires_at"`
+}
+
+// Status represents the generation status of an export as it is sent to the user.
+type Status struct {
+	ID          string     `json:"id"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// File represents a generated export file.
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
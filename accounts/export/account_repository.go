@@ -0,0 +1,149 @@
+package export
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"untitled_game/core/postgres"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrExportNotFound is used when an export does not exist, belongs to another account, or has
+// expired.
+var ErrExportNotFound = errors.New("export not found")
+
+// ErrExportInProgress is used when an export is requested while another export for the same account
+// is still being generated.
+var ErrExportInProgress = errors.New("export in progress")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	GetAccount(id int) (json.RawMessage, error)
+	Create(e Export) error
+	Get(accountID int, exportID string) (Export, error)
+	GetData(accountID int, exportID string) ([]byte, error)
+	Requeue(claimedBefore time.Time) (int, error)
+	Claim() (Export, error)
+	Complete(exportID string, data []byte, expiresAt time.Time) error
+	Fail(exportID string) error
+	DeleteExpired() error
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// GetAccount retrieves the accounts table row of the account with the given id as a json object.
+// Password hashes and tokens are left out, since they are secrets rather than personal data.
+func (r *accountRepository) GetAccount(id int) (json.RawMessage, error) {
+	const q = `SELECT to_jsonb(a) - 'password' - 'verification_token' - 'guest_device_hash' FROM accounts a WHERE id = $1`
+
+	var account []byte
+	if err := r.db.Get(&account, q, id); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(account), nil
+}
+
+// Create inserts a new pending export.
+func (r *accountRepository) Create(e Export) error {
+	const q = `INSERT INTO data_exports (id, account_id, format, status) VALUES ($1, $2, $3, $4)`
+
+	if _, err := r.db.Exec(q, e.ID, e.AccountID, e.Format, e.Status); err != nil {
+		if postgres.IsUniqueViolationError(err) {
+			return ErrExportInProgress
+		}
+		return err
+	}
+	return nil
+}
+
+// Get retrieves an unexpired export of the account with the given id.
+func (r *accountRepository) Get(accountID int, exportID string) (Export, error) {
+	const q = `SELECT id, account_id, format, status, created_at, completed_at, expires_at FROM data_exports WHERE id = $1 AND account_id = $2 AND (expires_at IS NULL OR expires_at > now())`
+
+	var e Export
+	if err := r.db.Get(&e, q, exportID, accountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e, ErrExportNotFound
+		}
+		return e, err
+	}
+	return e, nil
+}
+
+// GetData retrieves the generated file of a completed, unexpired export of the account with the
+// given id.
+func (r *accountRepository) GetData(accountID int, exportID string) ([]byte, error) {
+	const q = `SELECT data FROM data_exports WHERE id = $1 AND account_id = $2 AND status = 'completed' AND expires_at > now()`
+
+	var data []byte
+	if err := r.db.Get(&data, q, exportID, accountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Requeue marks running exports that were claimed before the given time as pending again, so that
+// exports that were abandoned by a server that stopped while generating them are generated again.
+// The number of requeued exports is returned.
+func (r *accountRepository) Requeue(claimedBefore time.Time) (int, error) {
+	const q = `UPDATE data_exports SET status = 'pending', claimed_at = NULL WHERE status = 'running' AND claimed_at < $1`
+
+	res, err := r.db.Exec(q, claimedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Claim marks the oldest pending export as running and returns it. Exports that are claimed by
+// another server are skipped. If there are no pending exports, then ErrExportNotFound is returned.
+func (r *accountRepository) Claim() (Export, error) {
+	const q = `UPDATE data_exports SET status = 'running', claimed_at = now() WHERE id = (SELECT id FROM data_exports WHERE status = 'pending' ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, account_id, format, status, created_at, completed_at, expires_at`
+
+	var e Export
+	if err := r.db.Get(&e, q); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e, ErrExportNotFound
+		}
+		return e, err
+	}
+	return e, nil
+}
+
+// Complete stores the generated file of an export and marks it as completed.
+func (r *accountRepository) Complete(exportID string, data []byte, expiresAt time.Time) error {
+	const q = `UPDATE data_exports SET status = 'completed', data = $2, completed_at = now(), expires_at = $3 WHERE id = $1`
+
+	_, err := r.db.Exec(q, exportID, data, expiresAt)
+	return err
+}
+
+// Fail marks an export as failed.
+func (r *accountRepository) Fail(exportID string) error {
+	const q = `UPDATE data_exports SET status = 'failed', completed_at = now(), expires_at = now() + interval '1 day' WHERE id = $1`
+
+	_, err := r.db.Exec(q, exportID)
+	return err
+}
+
+// DeleteExpired deletes exports that have expired.
+func (r *accountRepository) DeleteExpired() error {
+	const q = `DELETE FROM data_exports WHERE expires_at <= now()`
+
+	_, err := r.db.Exec(q)
+	return err
+}
@@ -0,0 +1,201 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"untitled_game/core/token"
+)
+
+// Service provides personal data export related services.
+type Service interface {
+	Request(id int, req Request) (Status, error)
+	Get(id int, exportID string) (Status, error)
+	Download(id int, exportID string) (File, error)
+	Process() (int, error)
+}
+
+// ServiceConfig represents configuration options for a personal data export service. The account
+// row is always exported, followed by the data of each source. Completed exports can be downloaded
+// until the retention period has passed. Exports that are still running once the claim timeout has
+// passed are assumed to have been abandoned and are generated again.
+type ServiceConfig struct {
+	Sources      []Source
+	Retention    time.Duration
+	ClaimTimeout time.Duration
+}
+
+type service struct {
+	accounts     AccountRepository
+	sources      []Source
+	retention    time.Duration
+	claimTimeout time.Duration
+}
+
+// NewService creates a new personal data export service.
+func NewService(accounts AccountRepository, cfg ServiceConfig) Service {
+	return &service{
+		accounts:     accounts,
+		sources:      cfg.Sources,
+		retention:    cfg.Retention,
+		claimTimeout: cfg.ClaimTimeout,
+	}
+}
+
+// Request queues a new export for the account with the given id. The export is generated in the
+// background by Process.
+func (s *service) Request(id int, req Request) (Status, error) {
+	if req.Format == "" {
+		req.Format = FormatJSON
+	}
+
+	exportID, err := token.Generate(16)
+	if err != nil {
+		return Status{}, err
+	}
+
+	e := Export{
+		ID:        exportID,
+		AccountID: id,
+		Format:    req.Format,
+		Status:    StatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.accounts.Create(e); err != nil {
+		return Status{}, err
+	}
+	return newStatus(e), nil
+}
+
+// Get retrieves the generation status of an export of the account with the given id.
+func (s *service) Get(id int, exportID string) (Status, error) {
+	e, err := s.accounts.Get(id, exportID)
+	if err != nil {
+		return Status{}, err
+	}
+	return newStatus(e), nil
+}
+
+// Download retrieves the generated file of a completed export of the account with the given id.
+func (s *service) Download(id int, exportID string) (File, error) {
+	e, err := s.accounts.Get(id, exportID)
+	if err != nil {
+		return File{}, err
+	}
+
+	data, err := s.accounts.GetData(id, exportID)
+	if err != nil {
+		return File{}, err
+	}
+
+	f := File{Name: "export-" + e.CreatedAt.UTC().Format("20060102") + "." + e.Format, Data: data}
+	if e.Format == FormatZIP {
+		f.ContentType = "application/zip"
+	} else {
+		f.ContentType = "application/json"
+	}
+	return f, nil
+}
+
+// Process generates all pending exports, requeues abandoned exports and deletes exports that have
+// expired. The number of generated exports is returned. An export that cannot be generated is marked
+// as failed, and the remaining exports are still generated. The error of the first failed export is
+// returned once all exports have been processed.
+func (s *service) Process() (int, error) {
+	if err := s.accounts.DeleteExpired(); err != nil {
+		return 0, err
+	}
+
+	if _, err := s.accounts.Requeue(time.Now().Add(-s.claimTimeout)); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	var genErr error
+	for {
+		e, err := s.accounts.Claim()
+		if err != nil {
+			if errors.Is(err, ErrExportNotFound) {
+				return n, genErr
+			}
+			return n, err
+		}
+
+		data, err := s.generate(e)
+		if err != nil {
+			if failErr := s.accounts.Fail(e.ID); failErr != nil {
+				return n, failErr
+			}
+			if genErr == nil {
+				genErr = fmt.Errorf("generate export %s: %w", e.ID, err)
+			}
+			continue
+		}
+
+		if err := s.accounts.Complete(e.ID, data, time.Now().Add(s.retention)); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// generate collects the data of the export account from the account row and every source, and
+// encodes it in the export format. JSON exports contain a single object with a key for each source,
+// while ZIP exports contain a JSON file for each source.
+func (s *service) generate(e Export) ([]byte, error) {
+	account, err := s.accounts.GetAccount(e.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{"account"}
+	data := map[string]interface{}{"account": account}
+	for _, src := range s.sources {
+		d, err := src.Export(e.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", src.Name(), err)
+		}
+		names = append(names, src.Name())
+		data[src.Name()] = d
+	}
+
+	if e.Format != FormatZIP {
+		return json.MarshalIndent(data, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		b, err := json.MarshalIndent(data[name], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		w, err := zw.Create(name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newStatus creates the status of an export.
+func newStatus(e Export) Status {
+	st := Status{ID: e.ID, Format: e.Format, Status: e.Status, CreatedAt: e.CreatedAt}
+	if e.CompletedAt.Valid {
+		st.CompletedAt = &e.CompletedAt.Time
+	}
+	if e.ExpiresAt.Valid {
+		st.ExpiresAt = &e.ExpiresAt.Time
+	}
+	return st
+}
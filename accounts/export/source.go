@@ -0,0 +1,30 @@
+package export
+
+// Source provides a method Export that collects the data of a subsystem that is tied to an account.
+// Each source is exported under its name. Subsystems that store personal data register a source with
+// the export service so that the data is included in every export.
+type Source interface {
+	Name() string
+	Export(id int) (interface{}, error)
+}
+
+type source struct {
+	name string
+	fn   func(id int) (interface{}, error)
+}
+
+// NewSource creates a new data source from a function that collects the data of the account with the
+// given id.
+func NewSource(name string, fn func(id int) (interface{}, error)) Source {
+	return &source{name, fn}
+}
+
+// Name returns the name of the data source.
+func (s *source) Name() string {
+	return s.name
+}
+
+// Export collects the data of the account with the given id.
+func (s *source) Export(id int) (interface{}, error) {
+	return s.fn(id)
+}
@@ -0,0 +1,42 @@
+package guest
+
+import (
+	"untitled_game/core/hasher"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+// Guest represents the data required to log in as a guest. The device id is generated by the client
+// and identifies the guest account, so it must be kept secret by the client. The optional device
+// name is stored with the session that is created.
+type Guest struct {
+	DeviceID string `json:"device_id"`
+	Device   string `json:"device"`
+}
+
+// Validate validates guest data.
+func (g Guest) Validate() error {
+	return validation.ValidateStruct(&g,
+		validation.Field(&g.DeviceID, validation.Required, validation.RuneLength(16, 128)),
+		validation.Field(&g.Device, validation.RuneLength(0, 64)),
+	)
+}
+
+// Upgrade represents the email and password that are attached to a guest account to turn it into a
+// regular account. The invite code is required while registration is invite-only, and is otherwise
+// optional.
+type Upgrade struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
+}
+
+// Validate validates upgrade data.
+func (u Upgrade) Validate() error {
+	return validation.ValidateStruct(&u,
+		validation.Field(&u.Email, validation.Required, is.Email),
+		validation.Field(&u.Password, hasher.PasswordRules...),
+		validation.Field(&u.InviteCode, validation.RuneLength(0, 64)),
+	)
+}
@@ -0,0 +1,110 @@
+package guest
+
+import (
+	"errors"
+	"time"
+	"untitled_game/accounts/invite"
+	"untitled_game/core/normalize"
+	"untitled_game/core/postgres"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountExists is used when a guest account cannot be upgraded due to the supplied email
+// address already being in use.
+var ErrAccountExists = errors.New("account already exists")
+
+// ErrInvalidInvite is used when a guest account cannot be upgraded because its invite code does not
+// exist, has expired, has been revoked, or has already been used the maximum number of times.
+var ErrInvalidInvite = errors.New("invalid invite code")
+
+// ErrNotGuest is used when attempting to upgrade an account that is not a guest account.
+var ErrNotGuest = errors.New("account is not a guest account")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Login(deviceHash string) (int, error)
+	Upgrade(id int, account Upgrade, tokenHash string) error
+	DeleteExpired(before time.Time) ([]int, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Login retrieves the id of the guest account with the given device id hash, creating the account
+// if it does not exist, and updates the last login time of the account.
+func (r *accountRepository) Login(deviceHash string) (int, error) {
+	const q = `INSERT INTO accounts (guest_device_hash, last_login_at) VALUES ($1, now()) ON CONFLICT (guest_device_hash) DO UPDATE SET last_login_at = now() RETURNING id`
+
+	var id int
+	if err := r.db.Get(&id, q, deviceHash); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Upgrade attaches an email address and password to the guest account with the given id along with
+// the hash of a verification token. The device id of the account no longer grants access once the
+// account has been upgraded. If the upgrade has an invite code, then the code is claimed before the
+// account is updated and redeemed in the same transaction, so that a code is only used up if the
+// account is upgraded, and ErrInvalidInvite is returned for an invalid code whether or not the email
+// is in use.
+func (r *accountRepository) Upgrade(id int, account Upgrade, tokenHash string) error {
+	const q = `UPDATE accounts SET email = $2, normalized_email = $3, password = $4, guest_device_hash = NULL, verification_token = $5, verification_token_expires_at = now() + interval '1 day' WHERE id = $1 AND email IS NULL AND deleted_at IS NULL`
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if account.InviteCode != "" {
+		if err := invite.Claim(tx, account.InviteCode); err != nil {
+			if errors.Is(err, invite.ErrInvalidCode) {
+				return ErrInvalidInvite
+			}
+			return err
+		}
+	}
+
+	res, err := tx.Exec(q, id, account.Email, normalize.Email(account.Email), account.Password, tokenHash)
+	if err != nil {
+		if postgres.IsUniqueViolationError(err) {
+			return ErrAccountExists
+		}
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotGuest
+	}
+
+	if account.InviteCode != "" {
+		if err := invite.Redeem(tx, account.InviteCode, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteExpired deletes guest accounts that have not logged in since before the given time and
+// returns the ids of the deleted accounts.
+func (r *accountRepository) DeleteExpired(before time.Time) ([]int, error) {
+	const q = `DELETE FROM accounts WHERE email IS NULL AND deleted_at IS NULL AND COALESCE(last_login_at, created_at) < $1 RETURNING id`
+
+	var ids []int
+	if err := r.db.Select(&ids, q, before); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
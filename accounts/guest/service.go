@@ -0,0 +1,160 @@
+package guest
+
+import (
+	"strings"
+	"time"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/invite"
+	"untitled_game/accounts/session"
+	"untitled_game/core/hasher"
+	"untitled_game/core/token"
+)
+
+// Service provides guest account related services.
+type Service interface {
+	Login(g Guest, meta session.Metadata) (session.Token, error)
+	Upgrade(sess session.Session, u Upgrade, meta session.Metadata) error
+	Purge() (int, error)
+}
+
+// verifier sends the email verification token of a newly upgraded account to its owner.
+type verifier interface {
+	Send(email string, token string) error
+}
+
+// banChecker checks whether an account is banned.
+type banChecker interface {
+	Check(id int) error
+}
+
+// permissionLoader loads the permissions that are granted to an account by its roles.
+type permissionLoader interface {
+	Permissions(id int) ([]string, error)
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+// ServiceConfig represents configuration options for a guest account service. Guest accounts that
+// have not been upgraded are purged once they have not logged in for the expiry duration. When
+// invites are required, guest accounts can only be upgraded with a valid invite code.
+type ServiceConfig struct {
+	Expiry        time.Duration
+	RequireInvite bool
+}
+
+type service struct {
+	sess     session.Store
+	accounts AccountRepository
+	hasher   hasher.Hasher
+	verifier verifier
+	bans     banChecker
+	perms    permissionLoader
+	audit    auditor
+	expiry   time.Duration
+	invite   bool
+}
+
+// NewService creates a new guest account service. The verifier should deliver messages in the
+// background, so that a failed delivery does not fail an upgrade that has already been stored.
+func NewService(sess session.Store, accounts AccountRepository, hasher hasher.Hasher, verifier verifier, bans banChecker, perms permissionLoader, audit auditor, cfg ServiceConfig) Service {
+	return &service{
+		sess:     sess,
+		accounts: accounts,
+		hasher:   hasher,
+		verifier: verifier,
+		bans:     bans,
+		perms:    perms,
+		audit:    audit,
+		expiry:   cfg.Expiry,
+		invite:   cfg.RequireInvite,
+	}
+}
+
+// Login logs in to the guest account that belongs to the supplied device id, creating the account if
+// it does not exist yet. A new session is added to the session store for the guest account along
+// with the supplied session metadata. If the guest account is banned, then a ban.BannedError is
+// returned.
+func (s *service) Login(g Guest, meta session.Metadata) (session.Token, error) {
+	id, err := s.accounts.Login(token.Hash(g.DeviceID))
+	if err != nil {
+		return session.Token{}, err
+	}
+
+	if err := s.bans.Check(id); err != nil {
+		return session.Token{}, err
+	}
+
+	sess, err := session.New(id)
+	if err != nil {
+		return session.Token{}, err
+	}
+
+	sess.Permissions, err = s.perms.Permissions(id)
+	if err != nil {
+		return session.Token{}, err
+	}
+	return s.sess.Add(sess, meta)
+}
+
+// Upgrade attaches an email address and password to the guest account of the current session and
+// sends a verification email to the account email address. The account keeps its id, so all
+// progress is kept, and existing sessions remain valid. If an invite code is supplied, then it is
+// redeemed for the account. If invites are required and no invite code is supplied, then
+// ErrInvalidInvite is returned.
+func (s *service) Upgrade(sess session.Session, u Upgrade, meta session.Metadata) error {
+	u.InviteCode = invite.Normalize(u.InviteCode)
+	if s.invite && u.InviteCode == "" {
+		return ErrInvalidInvite
+	}
+
+	hashedPw, err := s.hasher.Hash(u.Password)
+	if err != nil {
+		return err
+	}
+
+	t, err := token.Generate(32)
+	if err != nil {
+		return err
+	}
+
+	u.Email = strings.ToLower(u.Email)
+	u.Password = hashedPw
+
+	if err := s.accounts.Upgrade(sess.ID, u, token.Hash(t)); err != nil {
+		return err
+	}
+
+	s.record(audit.EventGuestUpgraded, sess.ID, meta, nil)
+	return s.verifier.Send(u.Email, t)
+}
+
+// Purge deletes guest accounts that have expired and removes their sessions from the session store.
+// The number of deleted accounts is returned.
+func (s *service) Purge() (int, error) {
+	ids, err := s.accounts.DeleteExpired(time.Now().Add(-s.expiry))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.sess.RemoveAll(session.Session{ID: id}); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// record records an audit event that was caused by and affects the account with the given id.
+func (s *service) record(eventType string, id int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"untitled_game/accounts/admin"
+	"untitled_game/accounts/auth"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/role"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// defaultSearchLimit is the number of accounts that are returned by an account search when no limit
+// is supplied.
+const defaultSearchLimit = 20
+
+// errNotBanned is sent as an http response when attempting to lift the bans of an account that is
+// not banned.
+var errNotBanned = api.Error{Message: "Account is not banned.", Status: http.StatusNotFound}
+
+// errAccountIsGuest is sent as an http response when an admin attempts an action that requires an
+// email address on a guest account.
+var errAccountIsGuest = api.Error{Message: "Account is a guest account.", Status: http.StatusConflict}
+
+// errAccountOutranked is sent as an http response when an admin attempts an action on an account
+// that holds more privileged roles than the admin.
+var errAccountOutranked = api.Error{Message: "Account holds more privileged roles.", Status: http.StatusForbidden}
+
+type adminHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   admin.Service
+}
+
+func (h *adminHandler) searchAccounts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := admin.Search{Query: query.Get("q"), Limit: defaultSearchLimit}
+
+	errs := validation.Errors{}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs["limit"] = validation.ErrInInvalid
+		}
+		search.Limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs["offset"] = validation.ErrInInvalid
+		}
+		search.Offset = n
+	}
+	if len(errs) > 0 {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(errs))
+		return
+	}
+
+	if err := search.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	page, err := h.s.Search(search)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, page)
+}
+
+func (h *adminHandler) getAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	details, err := h.s.Get(id)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.Respond(w, details)
+}
+
+func (h *adminHandler) listSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	sessions, err := h.s.Sessions(id)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.Respond(w, sessions)
+}
+
+func (h *adminHandler) deleteSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.Logout(session.GetSession(r).ID, id, meta); err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
+
+func (h *adminHandler) verifyAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.Verify(session.GetSession(r).ID, id, meta); err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
+
+func (h *adminHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.ResetPassword(session.GetSession(r).ID, id, meta); err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
+
+func (h *adminHandler) listBans(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	bans, err := h.s.Bans(id)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, bans)
+}
+
+func (h *adminHandler) banAccount(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	var b ban.NewBan
+	if err := h.dec.Decode(w, r, &b); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := b.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	created, err := h.s.Ban(session.GetSession(r).ID, id, b, meta)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.Respond(w, created)
+}
+
+func (h *adminHandler) liftBans(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.LiftBans(session.GetSession(r).ID, id, meta); err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
+
+func (h *adminHandler) getRoles(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	roles, err := h.s.Roles(id)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, roles)
+}
+
+func (h *adminHandler) setRoles(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	var a role.Assignment
+	if err := h.dec.Decode(w, r, &a); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := a.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	roles, err := h.s.SetRoles(session.GetSession(r).ID, id, a.Roles, meta)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+	h.res.Respond(w, roles)
+}
+
+func (h *adminHandler) unlock(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var u auth.Unlock
+	if err := h.dec.Decode(w, r, &u); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := u.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.Unlock(session.GetSession(r).ID, u, meta); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
+
+// respondError responds to a failed admin action with the http error that corresponds to the error.
+func (h *adminHandler) respondError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, admin.ErrAccountNotFound), errors.Is(err, ban.ErrAccountNotFound), errors.Is(err, role.ErrAccountNotFound):
+		h.res.RespondError(w, errAccountNotFound)
+	case errors.Is(err, admin.ErrGuestAccount):
+		h.res.RespondError(w, errAccountIsGuest)
+	case errors.Is(err, admin.ErrOutranked):
+		h.res.RespondError(w, errAccountOutranked)
+	case errors.Is(err, ban.ErrNotBanned):
+		h.res.RespondError(w, errNotBanned)
+	default:
+		h.res.RespondError(w, err)
+	}
+}
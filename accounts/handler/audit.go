@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"untitled_game/accounts/audit"
+	"untitled_game/core/api"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// defaultAuditLimit is the number of audit events that are returned when no limit is supplied.
+const defaultAuditLimit = 100
+
+type auditHandler struct {
+	res api.Responder
+	s   audit.Service
+}
+
+func (h *auditHandler) listEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := audit.Query{Type: query.Get("type"), Limit: defaultAuditLimit}
+
+	errs := validation.Errors{}
+	for name, dst := range map[string]*int{"account_id": &q.AccountID, "limit": &q.Limit, "offset": &q.Offset} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs[name] = validation.ErrInInvalid
+			}
+			*dst = n
+		}
+	}
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs[name] = validation.NewError("validation_is_rfc3339", "must be a valid RFC 3339 time")
+			}
+			*dst = t
+		}
+	}
+	if len(errs) > 0 {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(errs))
+		return
+	}
+
+	if err := q.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	events, err := h.s.List(q)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, events)
+}
@@ -2,20 +2,60 @@ package handler
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 	"untitled_game/accounts/auth"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/middleware"
 	"untitled_game/accounts/session"
+	"untitled_game/accounts/twofactor"
 	"untitled_game/core/api"
+	"untitled_game/core/captcha"
+	"untitled_game/core/jwt"
 )
 
 // errInvalidCredentials is sent as an http response when authentication fails due to an incorrect
 // account email address and password combination being supplied by the user.
 var errInvalidCredentials = api.Error{Message: "Invalid account credentials.", Status: http.StatusUnauthorized}
 
+// errSecondFactorRequired is sent as an http response when the supplied credentials are valid, but
+// the account has two-factor authentication enabled. The response details contain the challenge
+// token that must be exchanged for a session along with a second factor.
+var errSecondFactorRequired = api.Error{Message: "Second factor required.", Status: http.StatusUnauthorized}
+
+// errInvalidChallenge is sent as an http response when the supplied login challenge token does not
+// exist, has expired, or has been attempted too many times.
+var errInvalidChallenge = api.Error{Message: "Invalid or expired login challenge.", Status: http.StatusUnauthorized}
+
+// errInvalidRefreshToken is sent as an http response when the supplied refresh token does not exist,
+// has expired, or has already been exchanged.
+var errInvalidRefreshToken = api.Error{Message: "Invalid or expired refresh token.", Status: http.StatusUnauthorized}
+
+// errTooManyLoginAttempts is sent as an http response when a login attempt is rejected because
+// there have been too many failed attempts for the account email or the client ip address. The
+// Retry-After header is set to the number of seconds until another attempt is allowed.
+var errTooManyLoginAttempts = api.Error{Message: "Too many failed login attempts.", Status: http.StatusTooManyRequests}
+
+// errCaptchaRequired is sent as an http response when the client must pass bot verification on the
+// endpoint but no captcha response was supplied.
+var errCaptchaRequired = api.Error{Message: "Captcha required.", Status: http.StatusForbidden}
+
+// errCaptchaFailed is sent as an http response when the supplied captcha response is invalid,
+// expired or has already been used.
+var errCaptchaFailed = api.Error{Message: "Captcha verification failed.", Status: http.StatusForbidden}
+
+// challengeDetails represents the details of a second factor required error.
+type challengeDetails struct {
+	Challenge string `json:"challenge"`
+}
+
 type authHandler struct {
-	dec api.Decoder
-	res api.Responder
-	s   auth.Service
+	dec    api.Decoder
+	res    api.Responder
+	s      auth.Service
+	signer jwt.Signer
 }
 
 func (h *authHandler) getSession(w http.ResponseWriter, r *http.Request) {
@@ -36,10 +76,60 @@ func (h *authHandler) createSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.s.Login(creds)
+	meta := session.Metadata{Device: creds.Device, IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	token, err := h.s.Login(creds, meta)
+	if err != nil {
+		h.respondLoginError(w, err)
+		return
+	}
+	h.res.Respond(w, token)
+}
+
+func (h *authHandler) createSessionSecondFactor(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var sf auth.SecondFactor
+	if err := h.dec.Decode(w, r, &sf); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := sf.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	token, err := h.s.LoginSecondFactor(sf, meta)
+	if err != nil {
+		h.respondSecondFactorError(w, err)
+		return
+	}
+	h.res.Respond(w, token)
+}
+
+func (h *authHandler) refreshSession(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var ref auth.Refresh
+	if err := h.dec.Decode(w, r, &ref); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := ref.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	token, err := h.s.Refresh(ref, meta)
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
-			h.res.RespondError(w, errInvalidCredentials)
+		if errors.Is(err, session.ErrInvalidRefreshToken) || errors.Is(err, session.ErrRefreshTokenReused) {
+			h.res.RespondError(w, errInvalidRefreshToken)
 			return
 		}
 		h.res.RespondError(w, err)
@@ -50,7 +140,8 @@ func (h *authHandler) createSession(w http.ResponseWriter, r *http.Request) {
 
 func (h *authHandler) deleteSession(w http.ResponseWriter, r *http.Request) {
 	sess := session.GetSession(r)
-	if err := h.s.Logout(sess); err != nil {
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.Logout(sess, meta); err != nil {
 		if errors.Is(err, session.ErrSessionNotFound) {
 			h.res.RespondError(w, api.ErrUnauthorized)
 			return
@@ -58,6 +149,7 @@ func (h *authHandler) deleteSession(w http.ResponseWriter, r *http.Request) {
 		h.res.RespondError(w, err)
 		return
 	}
+
 	h.res.RespondStatus(w, http.StatusOK)
 }
 
@@ -75,14 +167,108 @@ func (h *authHandler) authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.s.Login(creds)
+	meta := session.Metadata{Device: creds.Device, IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	token, err := h.s.Authenticate(creds, meta)
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
-			h.res.RespondError(w, errInvalidCredentials)
-			return
-		}
+		h.respondLoginError(w, err)
+		return
+	}
+	h.res.Respond(w, token)
+}
+
+func (h *authHandler) authenticateSecondFactor(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var sf auth.SecondFactor
+	if err := h.dec.Decode(w, r, &sf); err != nil {
 		h.res.RespondError(w, err)
 		return
 	}
+
+	if err := sf.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	token, err := h.s.AuthenticateSecondFactor(sf, meta)
+	if err != nil {
+		h.respondSecondFactorError(w, err)
+		return
+	}
 	h.res.Respond(w, token)
 }
+
+func (h *authHandler) getJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	h.res.Respond(w, h.signer.JWKS())
+}
+
+// respondLoginError responds to a failed login attempt with the http error that corresponds to the
+// login error.
+func (h *authHandler) respondLoginError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		h.res.RespondError(w, errInvalidCredentials)
+		return
+	}
+	if errors.Is(err, captcha.ErrRequired) {
+		h.res.RespondError(w, errCaptchaRequired)
+		return
+	}
+	if errors.Is(err, captcha.ErrFailed) {
+		h.res.RespondError(w, errCaptchaFailed)
+		return
+	}
+
+	var sfErr *auth.SecondFactorRequiredError
+	if errors.As(err, &sfErr) {
+		h.res.RespondError(w, errSecondFactorRequired.WithDetails(challengeDetails{sfErr.Challenge}))
+		return
+	}
+
+	var bannedErr *ban.BannedError
+	if errors.As(err, &bannedErr) {
+		h.res.RespondError(w, middleware.ErrBanned.WithDetails(bannedErr.Details()))
+		return
+	}
+
+	var throttledErr *auth.ThrottledError
+	if errors.As(err, &throttledErr) {
+		h.res.RespondError(w, errTooManyLoginAttempts.WithHeader("Retry-After", retryAfter(throttledErr.RetryAfter)))
+		return
+	}
+	h.res.RespondError(w, err)
+}
+
+// respondSecondFactorError responds to a failed login challenge with the http error that
+// corresponds to the challenge error.
+func (h *authHandler) respondSecondFactorError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrInvalidChallenge) {
+		h.res.RespondError(w, errInvalidChallenge)
+		return
+	}
+	if errors.Is(err, twofactor.ErrInvalidCode) {
+		h.res.RespondError(w, errInvalidTwoFactorCode)
+		return
+	}
+
+	var throttledErr *auth.ThrottledError
+	if errors.As(err, &throttledErr) {
+		h.res.RespondError(w, errTooManyLoginAttempts.WithHeader("Retry-After", retryAfter(throttledErr.RetryAfter)))
+		return
+	}
+
+	var bannedErr *ban.BannedError
+	if errors.As(err, &bannedErr) {
+		h.res.RespondError(w, middleware.ErrBanned.WithDetails(bannedErr.Details()))
+		return
+	}
+	h.res.RespondError(w, err)
+}
+
+// retryAfter formats a duration as a Retry-After header value in whole seconds, rounding up.
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/deletion"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+type deletionHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   deletion.Service
+}
+
+func (h *deletionHandler) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req deletion.Request
+	if err := h.dec.Decode(w, r, &req); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	sess := session.GetSession(r)
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	scheduled, err := h.s.Delete(sess, req, meta)
+	if err != nil {
+		switch {
+		case errors.Is(err, deletion.ErrIncorrectPassword):
+			h.res.RespondError(w, errIncorrectPassword)
+		case errors.Is(err, deletion.ErrGuestAccount):
+			h.res.RespondError(w, errGuestAccount)
+		case errors.Is(err, deletion.ErrAccountNotFound):
+			h.res.RespondError(w, api.ErrUnauthorized)
+		default:
+			h.res.RespondError(w, err)
+		}
+		return
+	}
+
+	h.res.Respond(w, scheduled)
+}
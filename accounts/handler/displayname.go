@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"untitled_game/accounts/displayname"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// errDisplayNameTaken is sent as an http response when the user attempts to change their display
+// name to a name that is already in use.
+var errDisplayNameTaken = api.Error{Message: "Display name is already taken.", Status: http.StatusConflict}
+
+// errDisplayNameNotAllowed is sent as an http response when the user attempts to change their
+// display name to a name that is reserved or contains a blocked word.
+var errDisplayNameNotAllowed = api.Error{Message: "Display name is not allowed.", Status: http.StatusUnprocessableEntity}
+
+// errDisplayNameChangedRecently is sent as an http response when the user attempts to change their
+// display name before the cooldown since their previous change has passed.
+var errDisplayNameChangedRecently = api.Error{Message: "Display name was changed too recently.", Status: http.StatusConflict}
+
+// errAccountNotFound is sent as an http response when an account that is referenced by id does not
+// exist.
+var errAccountNotFound = api.Error{Message: "Account not found.", Status: http.StatusNotFound}
+
+type displayNameHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   displayname.Service
+}
+
+func (h *displayNameHandler) getDisplayName(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+
+	name, err := h.s.Get(sess.ID)
+	if err != nil {
+		if errors.Is(err, displayname.ErrAccountNotFound) {
+			h.res.RespondError(w, api.ErrUnauthorized)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, name)
+}
+
+func (h *displayNameHandler) changeDisplayName(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var c displayname.Change
+	if err := h.dec.Decode(w, r, &c); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := c.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	sess := session.GetSession(r)
+
+	name, err := h.s.Change(sess.ID, c)
+	if err != nil {
+		var validationErr validation.Errors
+		switch {
+		case errors.As(err, &validationErr):
+			h.res.RespondError(w, api.ErrValidationError.WithDetails(validationErr))
+		case errors.Is(err, displayname.ErrNameTaken):
+			h.res.RespondError(w, errDisplayNameTaken)
+		case errors.Is(err, displayname.ErrNameNotAllowed):
+			h.res.RespondError(w, errDisplayNameNotAllowed)
+		case errors.Is(err, displayname.ErrChangedRecently):
+			h.res.RespondError(w, errDisplayNameChangedRecently)
+		case errors.Is(err, displayname.ErrAccountNotFound):
+			h.res.RespondError(w, api.ErrUnauthorized)
+		default:
+			h.res.RespondError(w, err)
+		}
+		return
+	}
+	h.res.Respond(w, name)
+}
+
+func (h *displayNameHandler) getDisplayNameHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(api.Param(r, "id"))
+	if err != nil {
+		h.res.RespondError(w, errAccountNotFound)
+		return
+	}
+
+	history, err := h.s.History(id)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, history)
+}
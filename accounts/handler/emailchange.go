@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/emailchange"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// errEmailUnchanged is sent as an http response when the user attempts to change their email
+// address to the email address that they already have.
+var errEmailUnchanged = api.Error{Message: "Email address is unchanged.", Status: http.StatusConflict}
+
+// errEmailTaken is sent as an http response when an email change is confirmed for an email address
+// that is already in use by another account.
+var errEmailTaken = api.Error{Message: "Email address is already in use.", Status: http.StatusConflict}
+
+// errInvalidEmailChangeToken is sent as an http response when the supplied email change token does
+// not exist, has expired, or has already been used.
+var errInvalidEmailChangeToken = api.Error{Message: "Invalid or expired email change token.", Status: http.StatusBadRequest}
+
+type emailChangeHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   emailchange.Service
+}
+
+func (h *emailChangeHandler) changeEmail(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req emailchange.Request
+	if err := h.dec.Decode(w, r, &req); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	if err := h.s.Request(session.GetSession(r), req, meta); err != nil {
+		switch {
+		case errors.Is(err, emailchange.ErrIncorrectPassword):
+			h.res.RespondError(w, errIncorrectPassword)
+		case errors.Is(err, emailchange.ErrGuestAccount):
+			h.res.RespondError(w, errGuestAccount)
+		case errors.Is(err, emailchange.ErrSameEmail):
+			h.res.RespondError(w, errEmailUnchanged)
+		case errors.Is(err, emailchange.ErrAccountNotFound):
+			h.res.RespondError(w, api.ErrUnauthorized)
+		default:
+			h.res.RespondError(w, err)
+		}
+		return
+	}
+	h.res.RespondStatus(w, http.StatusAccepted)
+}
+
+func (h *emailChangeHandler) confirmEmail(w http.ResponseWriter, r *http.Request) {
+	h.handleToken(w, r, h.s.Confirm)
+}
+
+func (h *emailChangeHandler) revertEmail(w http.ResponseWriter, r *http.Request) {
+	h.handleToken(w, r, h.s.Revert)
+}
+
+// handleToken decodes an email change token from the request and passes it to the provided email
+// change service method.
+func (h *emailChangeHandler) handleToken(w http.ResponseWriter, r *http.Request, fn func(emailchange.Token, session.Metadata) error) {
+	defer r.Body.Close()
+
+	var t emailchange.Token
+	if err := h.dec.Decode(w, r, &t); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := t.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	if err := fn(t, meta); err != nil {
+		switch {
+		case errors.Is(err, emailchange.ErrInvalidToken):
+			h.res.RespondError(w, errInvalidEmailChangeToken)
+		case errors.Is(err, emailchange.ErrEmailTaken):
+			h.res.RespondError(w, errEmailTaken)
+		default:
+			h.res.RespondError(w, err)
+		}
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"untitled_game/accounts/export"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// errExportNotFound is sent as an http response when the user requests an export that does not
+// exist, has expired, or has not been generated yet.
+var errExportNotFound = api.Error{Message: "Export not found.", Status: http.StatusNotFound}
+
+// errExportInProgress is sent as an http response when the user requests an export while another
+// export is still being generated.
+var errExportInProgress = api.Error{Message: "An export is already in progress.", Status: http.StatusConflict}
+
+type exportHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   export.Service
+}
+
+func (h *exportHandler) requestExport(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req export.Request
+	if err := h.dec.Decode(w, r, &req); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	sess := session.GetSession(r)
+
+	status, err := h.s.Request(sess.ID, req)
+	if err != nil {
+		if errors.Is(err, export.ErrExportInProgress) {
+			h.res.RespondError(w, errExportInProgress)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, status)
+}
+
+func (h *exportHandler) getExport(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+
+	status, err := h.s.Get(sess.ID, api.Param(r, "id"))
+	if err != nil {
+		if errors.Is(err, export.ErrExportNotFound) {
+			h.res.RespondError(w, errExportNotFound)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, status)
+}
+
+func (h *exportHandler) downloadExport(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+
+	f, err := h.s.Download(sess.ID, api.Param(r, "id"))
+	if err != nil {
+		if errors.Is(err, export.ErrExportNotFound) {
+			h.res.RespondError(w, errExportNotFound)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+f.Name+"\"")
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.Data)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(f.Data)
+}
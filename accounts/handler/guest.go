@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/guest"
+	"untitled_game/accounts/middleware"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// errNotGuest is sent as an http response when the user attempts to upgrade an account that is
+// already a regular account.
+var errNotGuest = api.Error{Message: "Account is not a guest account.", Status: http.StatusConflict}
+
+// errGuestAccount is sent as an http response when a guest attempts an action that requires an
+// email address or a password.
+var errGuestAccount = api.Error{Message: "A registered account is required.", Status: http.StatusForbidden}
+
+type guestHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   guest.Service
+}
+
+func (h *guestHandler) createGuestSession(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var g guest.Guest
+	if err := h.dec.Decode(w, r, &g); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := g.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{Device: g.Device, IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	token, err := h.s.Login(g, meta)
+	if err != nil {
+		var bannedErr *ban.BannedError
+		if errors.As(err, &bannedErr) {
+			h.res.RespondError(w, middleware.ErrBanned.WithDetails(bannedErr.Details()))
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, token)
+}
+
+func (h *guestHandler) upgradeAccount(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var u guest.Upgrade
+	if err := h.dec.Decode(w, r, &u); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := u.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	sess := session.GetSession(r)
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.Upgrade(sess, u, meta); err != nil {
+		if errors.Is(err, guest.ErrAccountExists) {
+			h.res.RespondError(w, errAccountExists)
+			return
+		}
+		if errors.Is(err, guest.ErrNotGuest) {
+			h.res.RespondError(w, errNotGuest)
+			return
+		}
+		if errors.Is(err, guest.ErrInvalidInvite) {
+			h.res.RespondError(w, errInvalidInvite)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+
+	h.res.RespondStatus(w, http.StatusOK)
+}
@@ -2,30 +2,183 @@ package handler
 
 import (
 	"log"
+	"net"
 	"net/http"
+	"untitled_game/accounts/admin"
+	"untitled_game/accounts/audit"
 	"untitled_game/accounts/auth"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/deletion"
+	"untitled_game/accounts/displayname"
+	"untitled_game/accounts/emailchange"
+	"untitled_game/accounts/export"
+	"untitled_game/accounts/guest"
+	"untitled_game/accounts/introspect"
+	"untitled_game/accounts/invite"
+	"untitled_game/accounts/loginhistory"
 	"untitled_game/accounts/middleware"
+	"untitled_game/accounts/password"
+	"untitled_game/accounts/recovery"
 	"untitled_game/accounts/register"
+	"untitled_game/accounts/role"
 	"untitled_game/accounts/session"
+	"untitled_game/accounts/twofactor"
+	"untitled_game/accounts/verify"
 	"untitled_game/core/api"
+	"untitled_game/core/jwt"
+	"untitled_game/core/ratelimit"
 )
 
+// Services represents the services that handle requests to the http handler routes.
+type Services struct {
+	Auth         auth.Service
+	Register     register.Service
+	Verify       verify.Service
+	Recovery     recovery.Service
+	Password     password.Service
+	TwoFactor    twofactor.Service
+	Introspect   introspect.Service
+	Guest        guest.Service
+	DisplayName  displayname.Service
+	Deletion     deletion.Service
+	Export       export.Service
+	Ban          ban.Service
+	Admin        admin.Service
+	Audit        audit.Service
+	LoginHistory loginhistory.Service
+	EmailChange  emailchange.Service
+	Invite       invite.Service
+}
+
+// Config represents configuration options for the http handler. Rate limits are looked up by route
+// name, and routes without a configured rate limit are not rate limited. ProtectEnumeration must
+// match the enumeration protection setting of the register service. The signer publishes the public
+// keys used to verify game tokens. Service keys authenticate internal services that introspect
+// tokens, and the introspection route is only attached when introspection is enabled. The client ip
+// address of a request is only taken from the X-Real-IP header if it comes from a trusted proxy.
+type Config struct {
+	Signer             jwt.Signer
+	TrustedProxies     []*net.IPNet
+	Introspection      bool
+	ServiceKeys        []string
+	Limiter            ratelimit.Limiter
+	RateLimits         map[string]ratelimit.Limit
+	ProtectEnumeration bool
+}
+
 // New creates a new http handler and attaches routes.
-func New(log *log.Logger, sess session.Store, authService auth.Service, registerService register.Service) http.Handler {
+func New(log *log.Logger, sess session.Store, services Services, cfg Config) http.Handler {
 	dec := api.NewDecoder(api.StandardDecoderConfig)
 	res := api.NewResponder(log)
-	h := api.NewHandler(log, res)
+	h := api.NewHandler(log, res, api.TrustProxies(cfg.TrustedProxies))
+
+	authMw := middleware.Authenticate(res, sess, services.Ban)
+	serviceMw := middleware.APIKey(res, cfg.ServiceKeys)
+
+	// can creates middleware that only allows authenticated sessions with the given permission.
+	can := func(permission string) api.Middleware {
+		return middleware.RequirePermission(res, permission)
+	}
 
-	authMw := middleware.Authenticate(res, sess)
+	// limit creates rate limiting middleware for the named route if a rate limit is configured.
+	limit := func(name string, key ratelimit.KeyFunc) api.Middleware {
+		l, ok := cfg.RateLimits[name]
+		if !ok || cfg.Limiter == nil || l.Requests <= 0 || l.Window <= 0 {
+			return nil
+		}
+		return ratelimit.Middleware(res, cfg.Limiter, name, l, key)
+	}
 
-	authHandler := &authHandler{dec, res, authService}
+	authHandler := &authHandler{dec, res, services.Auth, cfg.Signer}
 	h.Handle(http.MethodGet, "/session", authHandler.getSession, authMw)
-	h.Handle(http.MethodPost, "/session", authHandler.createSession)
+	h.Handle(http.MethodPost, "/session", authHandler.createSession, limit("login", ratelimit.ByIP))
+	h.Handle(http.MethodPost, "/session/2fa", authHandler.createSessionSecondFactor, limit("login_2fa", ratelimit.ByIP))
+	h.Handle(http.MethodPost, "/session/refresh", authHandler.refreshSession, limit("session_refresh", ratelimit.ByIP))
 	h.Handle(http.MethodDelete, "/session", authHandler.deleteSession, authMw)
-	h.Handle(http.MethodPost, "/authenticate", authHandler.authenticate)
+	h.Handle(http.MethodPost, "/authenticate", authHandler.authenticate, limit("authenticate", ratelimit.ByIP))
+	h.Handle(http.MethodPost, "/authenticate/2fa", authHandler.authenticateSecondFactor, limit("authenticate_2fa", ratelimit.ByIP))
+	h.Handle(http.MethodGet, "/.well-known/jwks.json", authHandler.getJWKS)
+
+	loginHistoryHandler := &loginHistoryHandler{dec, res, services.LoginHistory}
+	h.Handle(http.MethodGet, "/account/logins", loginHistoryHandler.listLogins, authMw)
+	h.Handle(http.MethodPost, "/logins/revoke", loginHistoryHandler.revokeLogin, limit("login_revoke", ratelimit.ByIP))
+
+	sessionsHandler := &sessionsHandler{res, sess, services.Auth}
+	h.Handle(http.MethodGet, "/sessions", sessionsHandler.listSessions, authMw)
+	h.Handle(http.MethodDelete, "/sessions", sessionsHandler.deleteSessions, authMw)
+	h.Handle(http.MethodDelete, "/sessions/:key", sessionsHandler.deleteSession, authMw)
+
+	registerHandler := &registerHandler{dec, res, services.Register, cfg.ProtectEnumeration}
+	h.Handle(http.MethodPost, "/register", registerHandler.registerAccount, limit("register", ratelimit.ByIP))
+
+	guestHandler := &guestHandler{dec, res, services.Guest}
+	h.Handle(http.MethodPost, "/guest", guestHandler.createGuestSession, limit("guest", ratelimit.ByIP))
+	h.Handle(http.MethodPost, "/account/upgrade", guestHandler.upgradeAccount, authMw, limit("upgrade", middleware.ByAccount))
+
+	verifyHandler := &verifyHandler{dec, res, services.Verify}
+	h.Handle(http.MethodPost, "/verify", verifyHandler.verifyAccount, limit("verify", ratelimit.ByIP))
+	h.Handle(http.MethodPost, "/verify/resend", verifyHandler.resendVerification, authMw, limit("verify_resend", middleware.ByAccount))
+
+	recoveryHandler := &recoveryHandler{dec, res, services.Recovery}
+	h.Handle(http.MethodPost, "/password/forgot", recoveryHandler.forgotPassword, limit("password_forgot", ratelimit.ByIP))
+	h.Handle(http.MethodPost, "/password/reset", recoveryHandler.resetPassword, limit("password_reset", ratelimit.ByIP))
+
+	passwordHandler := &passwordHandler{dec, res, services.Password}
+	h.Handle(http.MethodPut, "/account/password", passwordHandler.changePassword, authMw, limit("password_change", middleware.ByAccount))
+
+	emailChangeHandler := &emailChangeHandler{dec, res, services.EmailChange}
+	h.Handle(http.MethodPut, "/account/email", emailChangeHandler.changeEmail, authMw, limit("email_change", middleware.ByAccount))
+	h.Handle(http.MethodPost, "/account/email/confirm", emailChangeHandler.confirmEmail, limit("email_confirm", ratelimit.ByIP))
+	h.Handle(http.MethodPost, "/account/email/revert", emailChangeHandler.revertEmail, limit("email_revert", ratelimit.ByIP))
+
+	twoFactorHandler := &twoFactorHandler{dec, res, services.TwoFactor}
+	h.Handle(http.MethodPost, "/account/2fa", twoFactorHandler.enroll, authMw)
+	h.Handle(http.MethodPost, "/account/2fa/confirm", twoFactorHandler.confirm, authMw, limit("two_factor", middleware.ByAccount))
+	h.Handle(http.MethodPost, "/account/2fa/disable", twoFactorHandler.disable, authMw, limit("two_factor", middleware.ByAccount))
+
+	deletionHandler := &deletionHandler{dec, res, services.Deletion}
+	h.Handle(http.MethodDelete, "/account", deletionHandler.deleteAccount, authMw, limit("account_delete", middleware.ByAccount))
+
+	exportHandler := &exportHandler{dec, res, services.Export}
+	h.Handle(http.MethodPost, "/account/exports", exportHandler.requestExport, authMw, limit("export", middleware.ByAccount))
+	h.Handle(http.MethodGet, "/account/exports/:id", exportHandler.getExport, authMw)
+	h.Handle(http.MethodGet, "/account/exports/:id/download", exportHandler.downloadExport, authMw)
+
+	displayNameHandler := &displayNameHandler{dec, res, services.DisplayName}
+	h.Handle(http.MethodGet, "/account/display-name", displayNameHandler.getDisplayName, authMw)
+	h.Handle(http.MethodPut, "/account/display-name", displayNameHandler.changeDisplayName, authMw, limit("display_name", middleware.ByAccount))
+
+	// Admin routes are authorized by the permissions of the authenticated account.
+	adminGroup := h.Group("/admin", authMw)
+	adminGroup.Handle(http.MethodGet, "/accounts/:id/display-names", displayNameHandler.getDisplayNameHistory, can(role.PermReadDisplayNames))
+
+	adminHandler := &adminHandler{dec, res, services.Admin}
+	adminGroup.Handle(http.MethodGet, "/accounts", adminHandler.searchAccounts, can(role.PermReadAccounts))
+	adminGroup.Handle(http.MethodGet, "/accounts/:id", adminHandler.getAccount, can(role.PermReadAccounts))
+	adminGroup.Handle(http.MethodGet, "/accounts/:id/sessions", adminHandler.listSessions, can(role.PermReadAccounts))
+	adminGroup.Handle(http.MethodDelete, "/accounts/:id/sessions", adminHandler.deleteSessions, can(role.PermLogoutAccounts))
+	adminGroup.Handle(http.MethodPost, "/accounts/:id/verify", adminHandler.verifyAccount, can(role.PermVerifyAccounts))
+	adminGroup.Handle(http.MethodPost, "/accounts/:id/password-reset", adminHandler.resetPassword, can(role.PermResetPasswords))
+	adminGroup.Handle(http.MethodGet, "/accounts/:id/bans", adminHandler.listBans, can(role.PermReadAccounts))
+	adminGroup.Handle(http.MethodPost, "/accounts/:id/bans", adminHandler.banAccount, can(role.PermBanAccounts))
+	adminGroup.Handle(http.MethodDelete, "/accounts/:id/bans", adminHandler.liftBans, can(role.PermBanAccounts))
+	adminGroup.Handle(http.MethodGet, "/accounts/:id/roles", adminHandler.getRoles, can(role.PermReadAccounts))
+	adminGroup.Handle(http.MethodPut, "/accounts/:id/roles", adminHandler.setRoles, can(role.PermManageRoles))
+	adminGroup.Handle(http.MethodPost, "/unlock", adminHandler.unlock, can(role.PermUnlockAccounts))
+
+	inviteHandler := &inviteHandler{dec, res, services.Invite}
+	adminGroup.Handle(http.MethodGet, "/invites", inviteHandler.listInvites, can(role.PermManageInvites))
+	adminGroup.Handle(http.MethodPost, "/invites", inviteHandler.mintInvites, can(role.PermManageInvites))
+	adminGroup.Handle(http.MethodDelete, "/invites", inviteHandler.revokeInvites, can(role.PermManageInvites))
+	adminGroup.Handle(http.MethodGet, "/invites/:code/redemptions", inviteHandler.listRedemptions, can(role.PermManageInvites))
+
+	auditHandler := &auditHandler{res, services.Audit}
+	adminGroup.Handle(http.MethodGet, "/audit-events", auditHandler.listEvents, can(role.PermReadAudit))
 
-	registerHandler := &registerHandler{dec, res, registerService}
-	h.Handle(http.MethodPost, "/register", registerHandler.registerAccount)
+	if cfg.Introspection {
+		introspectHandler := &introspectHandler{dec, res, services.Introspect}
+		h.Handle(http.MethodPost, "/introspect", introspectHandler.introspect, serviceMw)
+	}
 
 	return h
 }
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"untitled_game/accounts/introspect"
+	"untitled_game/core/api"
+)
+
+type introspectHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   introspect.Service
+}
+
+func (h *introspectHandler) introspect(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req introspect.Request
+	if err := h.dec.Decode(w, r, &req); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	result, err := h.s.Introspect(req)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	h.res.Respond(w, result)
+}
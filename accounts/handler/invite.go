@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"untitled_game/accounts/invite"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// defaultInviteLimit is the number of invite codes that are returned when no limit is supplied.
+const defaultInviteLimit = 100
+
+// errInviteNotFound is sent as an http response when an invite code does not exist.
+var errInviteNotFound = api.Error{Message: "Invite code not found.", Status: http.StatusNotFound}
+
+type inviteHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   invite.Service
+}
+
+func (h *inviteHandler) listInvites(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, offset := defaultInviteLimit, 0
+
+	errs := validation.Errors{}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs["limit"] = validation.ErrInInvalid
+		}
+		limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs["offset"] = validation.ErrInInvalid
+		}
+		offset = n
+	}
+	if err := validation.Validate(limit, validation.Min(1), validation.Max(1000)); err != nil {
+		errs["limit"] = err
+	}
+	if err := validation.Validate(offset, validation.Min(0)); err != nil {
+		errs["offset"] = err
+	}
+	if len(errs) > 0 {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(errs))
+		return
+	}
+
+	codes, err := h.s.List(limit, offset)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, codes)
+}
+
+func (h *inviteHandler) mintInvites(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var m invite.Mint
+	if err := h.dec.Decode(w, r, &m); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := m.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	codes, err := h.s.Mint(session.GetSession(r).ID, m, meta)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, codes)
+}
+
+func (h *inviteHandler) revokeInvites(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var rev invite.Revoke
+	if err := h.dec.Decode(w, r, &rev); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := rev.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	revoked, err := h.s.Revoke(session.GetSession(r).ID, rev, meta)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, revoked)
+}
+
+func (h *inviteHandler) listRedemptions(w http.ResponseWriter, r *http.Request) {
+	redemptions, err := h.s.Redemptions(api.Param(r, "code"))
+	if err != nil {
+		if errors.Is(err, invite.ErrCodeNotFound) {
+			h.res.RespondError(w, errInviteNotFound)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, redemptions)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/loginhistory"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// errInvalidRevokeToken is sent as an http response when the supplied revoke token does not exist,
+// has expired, or has already been used.
+var errInvalidRevokeToken = api.Error{Message: "Invalid or expired revoke token.", Status: http.StatusBadRequest}
+
+type loginHistoryHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   loginhistory.Service
+}
+
+func (h *loginHistoryHandler) listLogins(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+
+	logins, err := h.s.List(sess.ID)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, logins)
+}
+
+func (h *loginHistoryHandler) revokeLogin(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var rev loginhistory.Revoke
+	if err := h.dec.Decode(w, r, &rev); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := rev.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	if err := h.s.Revoke(rev); err != nil {
+		if errors.Is(err, loginhistory.ErrInvalidToken) {
+			h.res.RespondError(w, errInvalidRevokeToken)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/password"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// errIncorrectPassword is sent as an http response when the user attempts an action that requires
+// their current password and the supplied password is incorrect.
+var errIncorrectPassword = api.Error{Message: "Incorrect password.", Status: http.StatusForbidden}
+
+type passwordHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   password.Service
+}
+
+func (h *passwordHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var change password.Change
+	if err := h.dec.Decode(w, r, &change); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := change.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	sess := session.GetSession(r)
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.ChangePassword(sess, change, meta); err != nil {
+		if errors.Is(err, password.ErrIncorrectPassword) {
+			h.res.RespondError(w, errIncorrectPassword)
+			return
+		}
+		if errors.Is(err, password.ErrGuestAccount) {
+			h.res.RespondError(w, errGuestAccount)
+			return
+		}
+		if errors.Is(err, password.ErrAccountNotFound) {
+			h.res.RespondError(w, api.ErrUnauthorized)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+
+	h.res.RespondStatus(w, http.StatusOK)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/recovery"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// errInvalidResetToken is sent as an http response when the supplied password reset token does not
+// exist, has expired, or has already been used.
+var errInvalidResetToken = api.Error{Message: "Invalid or expired password reset token.", Status: http.StatusBadRequest}
+
+type recoveryHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   recovery.Service
+}
+
+func (h *recoveryHandler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req recovery.Request
+	if err := h.dec.Decode(w, r, &req); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	if err := h.s.RequestReset(req.Email); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusAccepted)
+}
+
+func (h *recoveryHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var reset recovery.Reset
+	if err := h.dec.Decode(w, r, &reset); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := reset.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	if err := h.s.ResetPassword(reset.Token, reset.Password, meta); err != nil {
+		if errors.Is(err, recovery.ErrInvalidToken) {
+			h.res.RespondError(w, errInvalidResetToken)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
@@ -4,17 +4,28 @@ import (
 	"errors"
 	"net/http"
 	"untitled_game/accounts/register"
+	"untitled_game/accounts/session"
 	"untitled_game/core/api"
+	"untitled_game/core/captcha"
 )
 
 // errAccountExists is sent as an http response when the user attempts to create an account with an
 // email address that is already in use.
 var errAccountExists = api.Error{Message: "Account already exists", Status: http.StatusConflict}
 
+// errInvalidInvite is sent as an http response when the user attempts to register without a valid
+// invite code while registration is invite-only, or with an invite code that cannot be redeemed.
+var errInvalidInvite = api.Error{Message: "Invalid or expired invite code.", Status: http.StatusForbidden}
+
 type registerHandler struct {
 	dec api.Decoder
 	res api.Responder
 	s   register.Service
+
+	// accepted is set when account enumeration protection is enabled. Successful registrations are
+	// answered with 202 Accepted instead of 201 Created, since the response is the same whether or
+	// not the email address was already in use.
+	accepted bool
 }
 
 func (h *registerHandler) registerAccount(w http.ResponseWriter, r *http.Request) {
@@ -31,13 +42,32 @@ func (h *registerHandler) registerAccount(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.s.CreateAccount(account); err != nil {
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	if err := h.s.CreateAccount(account, meta); err != nil {
 		if errors.Is(err, register.ErrAccountExists) {
 			h.res.RespondError(w, errAccountExists)
 			return
 		}
+		if errors.Is(err, register.ErrInvalidInvite) {
+			h.res.RespondError(w, errInvalidInvite)
+			return
+		}
+		if errors.Is(err, captcha.ErrRequired) {
+			h.res.RespondError(w, errCaptchaRequired)
+			return
+		}
+		if errors.Is(err, captcha.ErrFailed) {
+			h.res.RespondError(w, errCaptchaFailed)
+			return
+		}
 		h.res.RespondError(w, err)
 		return
 	}
+
+	if h.accepted {
+		h.res.RespondStatus(w, http.StatusAccepted)
+		return
+	}
 	h.res.RespondStatus(w, http.StatusCreated)
 }
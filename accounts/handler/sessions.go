@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/auth"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// errSessionNotFound is sent as an http response when the user attempts to revoke a session that
+// does not exist or has already expired.
+var errSessionNotFound = api.Error{Message: "Session not found.", Status: http.StatusNotFound}
+
+type sessionsHandler struct {
+	res  api.Responder
+	sess session.Store
+	s    auth.Service
+}
+
+func (h *sessionsHandler) listSessions(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+
+	sessions, err := h.sess.List(sess.ID)
+	if err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Key == sess.Key
+	}
+	h.res.Respond(w, sessions)
+}
+
+func (h *sessionsHandler) deleteSession(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.RevokeSession(sess, api.Param(r, "key"), meta); err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			h.res.RespondError(w, errSessionNotFound)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+
+	h.res.RespondStatus(w, http.StatusOK)
+}
+
+func (h *sessionsHandler) deleteSessions(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.RevokeSessions(sess, meta); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	h.res.RespondStatus(w, http.StatusOK)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/session"
+	"untitled_game/accounts/twofactor"
+	"untitled_game/core/api"
+)
+
+// errInvalidTwoFactorCode is sent as an http response when the supplied two-factor authentication
+// code or recovery code is incorrect or has already been used.
+var errInvalidTwoFactorCode = api.Error{Message: "Invalid two-factor authentication code.", Status: http.StatusUnauthorized}
+
+// errTwoFactorEnabled is sent as an http response when the user attempts to enroll in two-factor
+// authentication while it is already enabled.
+var errTwoFactorEnabled = api.Error{Message: "Two-factor authentication is already enabled.", Status: http.StatusConflict}
+
+// errTwoFactorNotEnrolled is sent as an http response when the user attempts to confirm two-factor
+// authentication without first starting enrollment.
+var errTwoFactorNotEnrolled = api.Error{Message: "Two-factor authentication enrollment has not been started.", Status: http.StatusConflict}
+
+// errTwoFactorNotEnabled is sent as an http response when the user attempts to disable two-factor
+// authentication while it is not enabled.
+var errTwoFactorNotEnabled = api.Error{Message: "Two-factor authentication is not enabled.", Status: http.StatusConflict}
+
+type twoFactorHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   twofactor.Service
+}
+
+func (h *twoFactorHandler) enroll(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+
+	enrollment, err := h.s.Enroll(sess.ID)
+	if err != nil {
+		if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+			h.res.RespondError(w, errTwoFactorEnabled)
+			return
+		}
+		if errors.Is(err, twofactor.ErrGuestAccount) {
+			h.res.RespondError(w, errGuestAccount)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.Respond(w, enrollment)
+}
+
+func (h *twoFactorHandler) confirm(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var code twofactor.Code
+	if err := h.dec.Decode(w, r, &code); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := code.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	sess := session.GetSession(r)
+
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+
+	codes, err := h.s.Confirm(sess.ID, code.Code, meta)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			h.res.RespondError(w, errInvalidTwoFactorCode)
+		case errors.Is(err, twofactor.ErrAlreadyEnabled):
+			h.res.RespondError(w, errTwoFactorEnabled)
+		case errors.Is(err, twofactor.ErrNotEnrolled):
+			h.res.RespondError(w, errTwoFactorNotEnrolled)
+		default:
+			h.res.RespondError(w, err)
+		}
+		return
+	}
+
+	h.res.Respond(w, codes)
+}
+
+func (h *twoFactorHandler) disable(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var code twofactor.Code
+	if err := h.dec.Decode(w, r, &code); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := code.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	sess := session.GetSession(r)
+	meta := session.Metadata{IP: api.ClientIP(r), UserAgent: r.UserAgent()}
+	if err := h.s.Disable(sess.ID, code.Code, meta); err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			h.res.RespondError(w, errInvalidTwoFactorCode)
+		case errors.Is(err, twofactor.ErrNotEnabled):
+			h.res.RespondError(w, errTwoFactorNotEnabled)
+		default:
+			h.res.RespondError(w, err)
+		}
+		return
+	}
+
+	h.res.RespondStatus(w, http.StatusOK)
+}
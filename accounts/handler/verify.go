@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"untitled_game/accounts/session"
+	"untitled_game/accounts/verify"
+	"untitled_game/core/api"
+)
+
+// errInvalidVerificationToken is sent as an http response when the supplied email verification
+// token does not exist or has expired.
+var errInvalidVerificationToken = api.Error{Message: "Invalid or expired verification token.", Status: http.StatusBadRequest}
+
+// errAlreadyVerified is sent as an http response when the user requests a new verification email
+// for an account that has already been verified.
+var errAlreadyVerified = api.Error{Message: "Account already verified.", Status: http.StatusConflict}
+
+type verifyHandler struct {
+	dec api.Decoder
+	res api.Responder
+	s   verify.Service
+}
+
+func (h *verifyHandler) verifyAccount(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var t verify.Token
+	if err := h.dec.Decode(w, r, &t); err != nil {
+		h.res.RespondError(w, err)
+		return
+	}
+
+	if err := t.Validate(); err != nil {
+		h.res.RespondError(w, api.ErrValidationError.WithDetails(err))
+		return
+	}
+
+	if err := h.s.Verify(t.Token); err != nil {
+		if errors.Is(err, verify.ErrInvalidToken) {
+			h.res.RespondError(w, errInvalidVerificationToken)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusOK)
+}
+
+func (h *verifyHandler) resendVerification(w http.ResponseWriter, r *http.Request) {
+	sess := session.GetSession(r)
+	if err := h.s.Resend(sess.ID); err != nil {
+		if errors.Is(err, verify.ErrAlreadyVerified) {
+			h.res.RespondError(w, errAlreadyVerified)
+			return
+		}
+		if errors.Is(err, verify.ErrGuestAccount) {
+			h.res.RespondError(w, errGuestAccount)
+			return
+		}
+		h.res.RespondError(w, err)
+		return
+	}
+	h.res.RespondStatus(w, http.StatusAccepted)
+}
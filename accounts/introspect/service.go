@@ -0,0 +1,80 @@
+package introspect
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"untitled_game/accounts/ban"
+	"untitled_game/accounts/session"
+)
+
+// Service provides token introspection services for internal services.
+type Service interface {
+	Introspect(req Request) (Result, error)
+}
+
+// sessionStore resolves access tokens to sessions without marking the sessions as used.
+type sessionStore interface {
+	Peek(sess session.Session) (session.Session, error)
+	Expiry(sess session.Session) (time.Time, error)
+}
+
+// banChecker checks whether an account is banned.
+type banChecker interface {
+	Check(id int) error
+}
+
+type service struct {
+	sess sessionStore
+	bans banChecker
+}
+
+// NewService creates a new token introspection service.
+func NewService(sess sessionStore, bans banChecker) Service {
+	return &service{sess, bans}
+}
+
+// Introspect checks whether an access token is active. The scope of an active token lists the
+// permissions of its session. A token that is malformed, has expired, belongs to a session that has
+// been removed, or belongs to a banned account is reported as inactive rather than as an error.
+func (s *service) Introspect(req Request) (Result, error) {
+	parsedSess, err := session.ParseToken(req.Token)
+	if err != nil {
+		return Result{}, nil
+	}
+
+	expiresAt, err := s.sess.Expiry(parsedSess)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			return Result{}, nil
+		}
+		return Result{}, err
+	}
+
+	sess, err := s.sess.Peek(parsedSess)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			return Result{}, nil
+		}
+		return Result{}, err
+	}
+
+	var bannedErr *ban.BannedError
+	if err := s.bans.Check(sess.ID); err != nil {
+		if errors.As(err, &bannedErr) {
+			return Result{}, nil
+		}
+		return Result{}, err
+	}
+
+	return Result{
+		Active:     true,
+		AccountID:  sess.ID,
+		Subject:    strconv.Itoa(sess.ID),
+		SessionKey: sess.Key,
+		ExpiresAt:  expiresAt.Unix(),
+		Scope:      strings.Join(sess.Permissions, " "),
+		TokenType:  "Bearer",
+	}, nil
+}
@@ -0,0 +1,29 @@
+package introspect
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Request represents a token that an internal service wants to check.
+type Request struct {
+	Token string `json:"token"`
+}
+
+// Validate validates introspection request data.
+func (r Request) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Token, validation.Required),
+	)
+}
+
+// Result represents the state of an introspected token. Inactive tokens carry no other information,
+// so that callers cannot learn anything about tokens that are not valid.
+type Result struct {
+	Active     bool   `json:"active"`
+	AccountID  int    `json:"account_id,omitempty"`
+	Subject    string `json:"sub,omitempty"`
+	SessionKey string `json:"session_key,omitempty"`
+	ExpiresAt  int64  `json:"exp,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+	TokenType  string `json:"token_type,omitempty"`
+}
@@ -0,0 +1,97 @@
+package invite
+
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// codeChars represents the set of characters that invite codes are made of. Characters that are
+// easily confused with each other, such as 0 and O, are left out so that codes can be typed in by
+// hand.
+const codeChars = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// Code represents an invite code. A code can be redeemed until it has been used the maximum number
+// of times, it expires, or it is revoked.
+type Code struct {
+	Code      string     `json:"code" db:"code"`
+	MaxUses   int        `json:"max_uses" db:"max_uses"`
+	Uses      int        `json:"uses" db:"uses"`
+	Note      string     `json:"note" db:"note"`
+	CreatedBy *int       `json:"created_by" db:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Mint represents a request to create a batch of invite codes. Codes are single-use unless a higher
+// maximum number of uses is supplied, and never expire unless an expiration time is supplied.
+type Mint struct {
+	Count     int        `json:"count"`
+	MaxUses   int        `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Note      string     `json:"note"`
+}
+
+// Validate validates invite code mint data.
+func (m Mint) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Count, validation.Required, validation.Min(1), validation.Max(1000)),
+		validation.Field(&m.MaxUses, validation.Min(0), validation.Max(1000000)),
+		validation.Field(&m.ExpiresAt, validation.By(func(interface{}) error {
+			if m.ExpiresAt != nil && !m.ExpiresAt.After(time.Now()) {
+				return validation.NewError("validation_expires_in_past", "must be in the future")
+			}
+			return nil
+		})),
+		validation.Field(&m.Note, validation.RuneLength(0, 200)),
+	)
+}
+
+// Revoke represents a request to revoke a batch of invite codes.
+type Revoke struct {
+	Codes []string `json:"codes"`
+}
+
+// Validate validates invite code revoke data.
+func (r Revoke) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Codes, validation.Required, validation.Length(1, 1000)),
+	)
+}
+
+// Revoked represents the number of invite codes that were revoked.
+type Revoked struct {
+	Revoked int `json:"revoked"`
+}
+
+// Redemption represents an account that was registered with an invite code.
+type Redemption struct {
+	Code       string    `json:"code" db:"code"`
+	AccountID  int       `json:"account_id" db:"account_id"`
+	RedeemedAt time.Time `json:"redeemed_at" db:"redeemed_at"`
+}
+
+// Normalize returns the canonical form of an invite code that was typed in by a user.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// generateCode creates a random invite code made up of three groups of four characters.
+func generateCode() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, c := range bytes {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(codeChars[c%byte(len(codeChars))])
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,135 @@
+package invite
+
+import (
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrCodeNotFound is used when an invite code does not exist.
+var ErrCodeNotFound = errors.New("invite code not found")
+
+// ErrInvalidCode is used when an invite code cannot be redeemed because it does not exist, has
+// expired, has been revoked, or has already been used the maximum number of times.
+var ErrInvalidCode = errors.New("invalid invite code")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Create(codes []string, m Mint, createdBy int) ([]Code, error)
+	Revoke(codes []string) (int, error)
+	List(limit int, offset int) ([]Code, error)
+	Redemptions(code string) ([]Redemption, error)
+	RedeemedBy(id int) ([]Redemption, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Claim uses up the invite code within a transaction, so that the code is only used up if the
+// account is created or upgraded in the same transaction. ErrInvalidCode is returned if the code
+// cannot be used. Codes are claimed before the account is written, so that an invalid code is
+// rejected the same way whether or not the email address is already in use.
+func Claim(tx *sqlx.Tx, code string) error {
+	const q = `UPDATE invite_codes SET uses = uses + 1 WHERE code = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now()) AND uses < max_uses`
+
+	res, err := tx.Exec(q, code)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// Redeem records that the invite code that was claimed in the same transaction was redeemed by the
+// account with the given id.
+func Redeem(tx *sqlx.Tx, code string, accountID int) error {
+	const q = `INSERT INTO invite_redemptions (code, account_id) VALUES ($1, $2)`
+
+	_, err := tx.Exec(q, code, accountID)
+	return err
+}
+
+// Create inserts a batch of invite codes that share the same settings.
+func (r *accountRepository) Create(codes []string, m Mint, createdBy int) ([]Code, error) {
+	const q = `INSERT INTO invite_codes (code, max_uses, note, created_by, expires_at)
+		SELECT unnest($1::text[]), $2, $3, $4, $5
+		RETURNING code, max_uses, uses, note, created_by, expires_at, revoked_at, created_at`
+
+	created := []Code{}
+	if err := r.db.Select(&created, q, pq.Array(codes), m.MaxUses, m.Note, createdBy, m.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Revoke revokes the invite codes that have not already been revoked and returns the number of
+// revoked codes.
+func (r *accountRepository) Revoke(codes []string) (int, error) {
+	const q = `UPDATE invite_codes SET revoked_at = now() WHERE code = ANY($1) AND revoked_at IS NULL`
+
+	res, err := r.db.Exec(q, pq.Array(codes))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// List retrieves a page of invite codes, most recent first.
+func (r *accountRepository) List(limit int, offset int) ([]Code, error) {
+	const q = `SELECT code, max_uses, uses, note, created_by, expires_at, revoked_at, created_at FROM invite_codes ORDER BY created_at DESC, code LIMIT $1 OFFSET $2`
+
+	codes := []Code{}
+	if err := r.db.Select(&codes, q, limit, offset); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Redemptions retrieves the accounts that were registered with the given invite code.
+func (r *accountRepository) Redemptions(code string) ([]Redemption, error) {
+	const (
+		qExists = `SELECT EXISTS (SELECT 1 FROM invite_codes WHERE code = $1)`
+		q       = `SELECT code, account_id, redeemed_at FROM invite_redemptions WHERE code = $1 ORDER BY redeemed_at`
+	)
+
+	var exists bool
+	if err := r.db.Get(&exists, qExists, code); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCodeNotFound
+	}
+
+	redemptions := []Redemption{}
+	if err := r.db.Select(&redemptions, q, code); err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// RedeemedBy retrieves the invite codes that were redeemed by the account with the given id.
+func (r *accountRepository) RedeemedBy(id int) ([]Redemption, error) {
+	const q = `SELECT code, account_id, redeemed_at FROM invite_redemptions WHERE account_id = $1 ORDER BY redeemed_at`
+
+	redemptions := []Redemption{}
+	if err := r.db.Select(&redemptions, q, id); err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
@@ -0,0 +1,100 @@
+package invite
+
+import (
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/session"
+)
+
+// Service provides invite code management services for admins. Invite codes are redeemed by the
+// register service when an account is created.
+type Service interface {
+	Mint(adminID int, m Mint, meta session.Metadata) ([]Code, error)
+	Revoke(adminID int, r Revoke, meta session.Metadata) (Revoked, error)
+	List(limit int, offset int) ([]Code, error)
+	Redemptions(code string) ([]Redemption, error)
+	RedeemedBy(id int) ([]Redemption, error)
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+type service struct {
+	accounts AccountRepository
+	audit    auditor
+}
+
+// NewService creates a new invite code service. Minting and revoking codes is recorded in the audit
+// log as an action of the admin that performed it.
+func NewService(accounts AccountRepository, audit auditor) Service {
+	return &service{accounts, audit}
+}
+
+// Mint creates a batch of invite codes on behalf of the admin with the given id.
+func (s *service) Mint(adminID int, m Mint, meta session.Metadata) ([]Code, error) {
+	if m.MaxUses == 0 {
+		m.MaxUses = 1
+	}
+
+	codes := make([]string, m.Count)
+	for i := range codes {
+		code, err := generateCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	created, err := s.accounts.Create(codes, m, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(audit.EventInvitesMinted, adminID, meta, m)
+	return created, nil
+}
+
+// Revoke revokes a batch of invite codes on behalf of the admin with the given id. Codes that do
+// not exist or have already been revoked are skipped.
+func (s *service) Revoke(adminID int, r Revoke, meta session.Metadata) (Revoked, error) {
+	codes := make([]string, len(r.Codes))
+	for i, code := range r.Codes {
+		codes[i] = Normalize(code)
+	}
+
+	n, err := s.accounts.Revoke(codes)
+	if err != nil {
+		return Revoked{}, err
+	}
+
+	s.record(audit.EventInvitesRevoked, adminID, meta, Revoke{codes})
+	return Revoked{n}, nil
+}
+
+// List retrieves a page of invite codes.
+func (s *service) List(limit int, offset int) ([]Code, error) {
+	return s.accounts.List(limit, offset)
+}
+
+// Redemptions retrieves the accounts that were registered with the given invite code.
+func (s *service) Redemptions(code string) ([]Redemption, error) {
+	return s.accounts.Redemptions(Normalize(code))
+}
+
+// RedeemedBy retrieves the invite codes that were redeemed by the account with the given id.
+func (s *service) RedeemedBy(id int) ([]Redemption, error) {
+	return s.accounts.RedeemedBy(id)
+}
+
+// record records an audit event for an action that was performed by the admin with the given id.
+// Invite codes are not tied to a single account, so the account of the event is not set.
+func (s *service) record(eventType string, adminID int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   adminID,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
@@ -0,0 +1,77 @@
+package loginhistory
+
+import (
+	"net"
+	"time"
+	"untitled_game/core/token"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Login represents a successful login that is added to the login history of an account. The session
+// key is empty for logins that did not create a session, such as game token authentication.
+type Login struct {
+	SessionKey  string
+	IP          string
+	Network     string
+	UserAgent   string
+	Device      string
+	Fingerprint string
+}
+
+// NewLogin creates a new login from the client that logged in. Clients are identified by a
+// fingerprint of their device name and user agent, and by the network that their ip address belongs
+// to.
+func NewLogin(sessionKey string, ip string, userAgent string, device string) Login {
+	return Login{
+		SessionKey:  sessionKey,
+		IP:          ip,
+		Network:     network(ip),
+		UserAgent:   userAgent,
+		Device:      device,
+		Fingerprint: token.Hash(device + "\x00" + userAgent),
+	}
+}
+
+// network returns the network that an ip address belongs to. IPv4 addresses are grouped by /24 and
+// IPv6 addresses by /48, which roughly corresponds to a single home or office network.
+func network(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+// Result represents the outcome of adding a login to the login history of an account.
+type Result struct {
+	Email      *string
+	FirstLogin bool
+	NewDevice  bool
+}
+
+// Entry represents a login in the login history of an account.
+type Entry struct {
+	IP        string     `json:"ip" db:"ip"`
+	UserAgent string     `json:"user_agent" db:"user_agent"`
+	Device    string     `json:"device" db:"device"`
+	NewDevice bool       `json:"new_device" db:"new_device"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Revoke represents a token from a new device notification that is used to revoke the session of
+// an unrecognized login.
+type Revoke struct {
+	Token string `json:"token"`
+}
+
+// Validate validates revoke data.
+func (r Revoke) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Token, validation.Required),
+	)
+}
@@ -0,0 +1,114 @@
+package loginhistory
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrInvalidToken is used when a revoke token does not exist, has expired, or has already been used.
+var ErrInvalidToken = errors.New("invalid revoke token")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Add(id int, l Login, revokeTokenHash string) (Result, error)
+	List(id int, limit int) ([]Entry, error)
+	Revoke(revokeTokenHash string, after time.Time) (int, string, error)
+	DeleteExpired(before time.Time) (int, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Add updates the last login time of the account with the given id and adds a login to its login
+// history. The login is from a new device if the account has logged in before, but never with the
+// same device fingerprint or from the same network.
+func (r *accountRepository) Add(id int, l Login, revokeTokenHash string) (Result, error) {
+	const (
+		qUpdate = `UPDATE accounts SET last_login_at = now() WHERE id = $1 RETURNING email`
+		qKnown  = `SELECT NOT EXISTS (SELECT 1 FROM login_history WHERE account_id = $1) AS first_login,
+			NOT EXISTS (SELECT 1 FROM login_history WHERE account_id = $1 AND device_fingerprint = $2)
+			OR NOT EXISTS (SELECT 1 FROM login_history WHERE account_id = $1 AND network = $3) AS new_device`
+		qInsert = `INSERT INTO login_history (account_id, session_key, ip, network, user_agent, device, device_fingerprint, new_device, revoke_token_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	var res Result
+	if err := tx.Get(&res.Email, qUpdate, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Result{}, ErrAccountNotFound
+		}
+		return Result{}, err
+	}
+
+	if err := tx.QueryRowx(qKnown, id, l.Fingerprint, l.Network).Scan(&res.FirstLogin, &res.NewDevice); err != nil {
+		return Result{}, err
+	}
+	res.NewDevice = res.NewDevice && !res.FirstLogin
+
+	if _, err := tx.Exec(qInsert, id, l.SessionKey, l.IP, l.Network, l.UserAgent, l.Device, l.Fingerprint, res.NewDevice, revokeTokenHash); err != nil {
+		return Result{}, err
+	}
+	return res, tx.Commit()
+}
+
+// List retrieves the most recent logins of the account with the given id.
+func (r *accountRepository) List(id int, limit int) ([]Entry, error) {
+	const q = `SELECT ip, user_agent, device, new_device, revoked_at, created_at FROM login_history WHERE account_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`
+
+	entries := []Entry{}
+	if err := r.db.Select(&entries, q, id, limit); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Revoke marks the login with the given revoke token hash as revoked and returns the id of its
+// account and the key of its session. Only logins from a new device that were made after the given
+// time can be revoked.
+func (r *accountRepository) Revoke(revokeTokenHash string, after time.Time) (int, string, error) {
+	const q = `UPDATE login_history SET revoked_at = now() WHERE revoke_token_hash = $1 AND new_device AND revoked_at IS NULL AND created_at > $2 RETURNING account_id, session_key`
+
+	var (
+		id  int
+		key string
+	)
+	if err := r.db.QueryRowx(q, revokeTokenHash, after).Scan(&id, &key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", ErrInvalidToken
+		}
+		return 0, "", err
+	}
+	return id, key, nil
+}
+
+// DeleteExpired deletes the logins that were made before the given time and returns the number of
+// deleted logins.
+func (r *accountRepository) DeleteExpired(before time.Time) (int, error) {
+	const q = `DELETE FROM login_history WHERE created_at < $1`
+
+	res, err := r.db.Exec(q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
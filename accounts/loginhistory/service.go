@@ -0,0 +1,163 @@
+package loginhistory
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+	"untitled_game/accounts/session"
+	"untitled_game/core/mail"
+	"untitled_game/core/token"
+)
+
+// historyLimit is the number of recent logins that are shown to the account owner.
+const historyLimit = 50
+
+// newDeviceEmail is the body of the email that is sent when an account logs in from a device or
+// network that it has not used before.
+const newDeviceEmail = `Your Untitled Game account was just used to log in from a new device or location.
+
+Device: %s
+Browser: %s
+IP address: %s
+Time: %s
+
+If this was you, you can safely ignore this email. If this wasn't you, visit the link below to
+sign the new device out, and then change your password:
+
+%s
+
+This link expires in %d hours.
+`
+
+// newDeviceTokenEmail is the body of the email that is sent when an account is used to sign in to a
+// game server from a device or network that it has not used before. Game server logins do not
+// create a session, so there is no session to revoke.
+const newDeviceTokenEmail = `Your Untitled Game account was just used to sign in to a game server from a new device or
+location.
+
+Device: %s
+Browser: %s
+IP address: %s
+Time: %s
+
+If this was you, you can safely ignore this email. If this wasn't you, change your password right
+away and sign out of all of your sessions.
+`
+
+// Service provides login history related services.
+type Service interface {
+	Record(id int, l Login)
+	List(id int) ([]Entry, error)
+	Revoke(r Revoke) error
+	Purge() (int, error)
+}
+
+type sessionStore interface {
+	Remove(sess session.Session) error
+}
+
+// ServiceConfig represents configuration options for a login history service. The link url is the
+// page that revokes the session of an unrecognized login, and links expire after the revoke ttl.
+// Logins are kept for the retention period.
+type ServiceConfig struct {
+	LinkURL   string
+	RevokeTTL time.Duration
+	Retention time.Duration
+}
+
+type service struct {
+	sess      sessionStore
+	accounts  AccountRepository
+	mailer    mail.Mailer
+	log       *log.Logger
+	linkURL   string
+	revokeTTL time.Duration
+	retention time.Duration
+}
+
+// NewService creates a new login history service. Logins that cannot be recorded are written to the
+// logger instead.
+func NewService(sess sessionStore, accounts AccountRepository, mailer mail.Mailer, log *log.Logger, cfg ServiceConfig) Service {
+	return &service{
+		sess:      sess,
+		accounts:  accounts,
+		mailer:    mailer,
+		log:       log,
+		linkURL:   cfg.LinkURL,
+		revokeTTL: cfg.RevokeTTL,
+		retention: cfg.Retention,
+	}
+}
+
+// Record updates the last login time of the account with the given id and adds the login to its
+// login history. If the login was made from a device or network that the account has not used
+// before, then the account owner is notified by email. If the login created a session, then the
+// email contains a link that revokes the session. Recording never fails the login, so a login that
+// cannot be recorded or notified is logged along with the error.
+func (s *service) Record(id int, l Login) {
+	if err := s.record(id, l); err != nil {
+		s.log.Printf("could not record login for account %d: %v", id, err)
+	}
+}
+
+// record adds the login to the login history of the account with the given id and notifies the
+// account owner of logins from new devices.
+func (s *service) record(id int, l Login) error {
+	t, err := token.Generate(32)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.accounts.Add(id, l, token.Hash(t))
+	if err != nil {
+		return err
+	}
+
+	if !res.NewDevice || res.Email == nil {
+		return nil
+	}
+
+	device := l.Device
+	if device == "" {
+		device = "Unknown device"
+	}
+	now := time.Now().UTC().Format(time.RFC1123)
+
+	body := fmt.Sprintf(newDeviceTokenEmail, device, l.UserAgent, l.IP, now)
+	if l.SessionKey != "" {
+		body = fmt.Sprintf(newDeviceEmail, device, l.UserAgent, l.IP, now, s.linkURL+"?token="+url.QueryEscape(t), int(s.revokeTTL.Hours()))
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      *res.Email,
+		Subject: "New login to your account",
+		Body:    body,
+	})
+}
+
+// List retrieves the recent logins of the account with the given id.
+func (s *service) List(id int) ([]Entry, error) {
+	return s.accounts.List(id, historyLimit)
+}
+
+// Revoke removes the session of the login that the revoke token was issued for. The token can only
+// be used once.
+func (s *service) Revoke(r Revoke) error {
+	id, key, err := s.accounts.Revoke(token.Hash(r.Token), time.Now().Add(-s.revokeTTL))
+	if err != nil {
+		return err
+	}
+
+	if err := s.sess.Remove(session.Session{ID: id, Key: key}); err != nil && !errors.Is(err, session.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Purge deletes the logins that are older than the retention period and returns the number of
+// deleted logins.
+func (s *service) Purge() (int, error) {
+	return s.accounts.DeleteExpired(time.Now().Add(-s.retention))
+}
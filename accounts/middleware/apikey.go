@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"untitled_game/core/api"
+)
+
+// APIKey validates a static api key from the X-API-Key header of the request. This is used to
+// protect routes that are only called by trusted internal tools. If the key does not match any of
+// the provided keys, then the middleware responds to the request with an unauthorized error.
+func APIKey(res api.Responder, keys []string) api.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			supplied := []byte(r.Header.Get("X-API-Key"))
+			if len(supplied) == 0 {
+				res.RespondError(w, api.ErrUnauthorized)
+				return
+			}
+
+			for _, key := range keys {
+				if subtle.ConstantTimeCompare(supplied, []byte(key)) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			res.RespondError(w, api.ErrUnauthorized)
+		}
+	}
+}
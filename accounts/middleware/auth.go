@@ -1,21 +1,32 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"untitled_game/accounts/ban"
 	"untitled_game/accounts/session"
 	"untitled_game/core/api"
 )
 
+// ErrBanned is sent as an http response when a banned account attempts to log in or use an existing
+// session. The response details contain the reason for the ban and the time that it expires.
+var ErrBanned = api.Error{Message: "Account is banned.", Status: http.StatusForbidden}
+
 type sessionStore interface {
 	Get(sess session.Session) (session.Session, error)
 }
 
+type banChecker interface {
+	Check(id int) error
+}
+
 // Authenticate validates a session token from the Authorization header of the request. If the
 // session store contains an entry for the provided token, then the token is considered valid
 // and the session is added to the request context. If the token is invalid, then the middleware
-// responds to the request with an unauthorized error.
-func Authenticate(res api.Responder, sessions sessionStore) api.Middleware {
+// responds to the request with an unauthorized error. If the account has been banned since the
+// session was created, then the middleware responds with a banned error.
+func Authenticate(res api.Responder, sessions sessionStore, bans banChecker) api.Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
@@ -35,6 +46,16 @@ func Authenticate(res api.Responder, sessions sessionStore) api.Middleware {
 				res.RespondError(w, api.ErrUnauthorized)
 				return
 			}
+
+			if err := bans.Check(sess.ID); err != nil {
+				var bannedErr *ban.BannedError
+				if errors.As(err, &bannedErr) {
+					res.RespondError(w, ErrBanned.WithDetails(bannedErr.Details()))
+					return
+				}
+				res.RespondError(w, err)
+				return
+			}
 			next.ServeHTTP(w, session.WithSession(r, sess))
 		}
 	}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"untitled_game/accounts/session"
+	"untitled_game/core/api"
+)
+
+// RequirePermission checks that the session of the request has the given permission. If it does not,
+// then the middleware responds to the request with a forbidden error. The middleware must be placed
+// after the Authenticate middleware.
+func RequirePermission(res api.Responder, permission string) api.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !session.GetSession(r).HasPermission(permission) {
+				res.RespondError(w, api.ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"untitled_game/accounts/session"
+)
+
+// ByAccount rate limits requests by the account that owns the session of the request. It must only
+// be used on routes that run the Authenticate middleware first.
+func ByAccount(r *http.Request) string {
+	return "account:" + strconv.Itoa(session.GetSession(r).ID)
+}
@@ -0,0 +1,22 @@
+package password
+
+import (
+	"untitled_game/core/hasher"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Change represents a request to change the password of the current account. The current password
+// is required to confirm that the request was made by the account owner.
+type Change struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Validate validates password change data.
+func (c Change) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.CurrentPassword, validation.Required),
+		validation.Field(&c.NewPassword, hasher.PasswordRules...),
+	)
+}
@@ -0,0 +1,66 @@
+package password
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrGuestAccount is used when attempting to change the password of a guest account, which does not
+// have a password until it is upgraded.
+var ErrGuestAccount = errors.New("guest account")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	GetPassword(id int) (string, error)
+	SetPassword(id int, password string) error
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// GetPassword retrieves the hashed password of the account with the given id.
+func (r *accountRepository) GetPassword(id int) (string, error) {
+	const q = `SELECT password FROM accounts WHERE id = $1`
+
+	var password sql.NullString
+	if err := r.db.Get(&password, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrAccountNotFound
+		}
+		return "", err
+	}
+	if !password.Valid {
+		return "", ErrGuestAccount
+	}
+	return password.String, nil
+}
+
+// SetPassword updates the hashed password of the account with the given id.
+func (r *accountRepository) SetPassword(id int, password string) error {
+	const q = `UPDATE accounts SET password = $2 WHERE id = $1`
+
+	res, err := r.db.Exec(q, id, password)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"errors"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/session"
+	"untitled_game/core/hasher"
+)
+
+// ErrIncorrectPassword is used when the supplied current password does not match the password of
+// the account.
+var ErrIncorrectPassword = errors.New("incorrect password")
+
+// Service provides password management related services.
+type Service interface {
+	ChangePassword(sess session.Session, change Change, meta session.Metadata) error
+}
+
+type sessionStore interface {
+	RemoveOthers(sess session.Session) error
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+type service struct {
+	sess     sessionStore
+	accounts AccountRepository
+	hasher   hasher.Hasher
+	audit    auditor
+}
+
+// NewService creates a new password management service.
+func NewService(sess sessionStore, accounts AccountRepository, hasher hasher.Hasher, audit auditor) Service {
+	return &service{sess, accounts, hasher, audit}
+}
+
+// ChangePassword changes the password of the account that owns the session after confirming the
+// current password. All sessions for the account other than the supplied session are revoked.
+func (s *service) ChangePassword(sess session.Session, change Change, meta session.Metadata) error {
+	current, err := s.accounts.GetPassword(sess.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.hasher.Compare(current, change.CurrentPassword); err != nil {
+		if errors.Is(err, hasher.ErrMismatch) {
+			return ErrIncorrectPassword
+		}
+		return err
+	}
+
+	hashedPw, err := s.hasher.Hash(change.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.accounts.SetPassword(sess.ID, hashedPw); err != nil {
+		return err
+	}
+	if err := s.sess.RemoveOthers(sess); err != nil {
+		return err
+	}
+
+	s.record(audit.EventPasswordChanged, sess.ID, meta, nil)
+	return nil
+}
+
+// record records an audit event that was caused by and affects the account with the given id.
+func (s *service) record(eventType string, id int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
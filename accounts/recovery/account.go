@@ -0,0 +1,41 @@
+package recovery
+
+import (
+	"untitled_game/core/hasher"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+// Account represents account info that is retrieved from the account repository when a password
+// reset is requested.
+type Account struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+}
+
+// Request represents a request to reset the password of the account with the given email address.
+type Request struct {
+	Email string `json:"email"`
+}
+
+// Validate validates password reset request data.
+func (r Request) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Email, validation.Required, is.Email),
+	)
+}
+
+// Reset represents a password reset token along with the new password to set for the account.
+type Reset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate validates password reset data.
+func (r Reset) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Token, validation.Required),
+		validation.Field(&r.Password, hasher.PasswordRules...),
+	)
+}
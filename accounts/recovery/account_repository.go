@@ -0,0 +1,89 @@
+package recovery
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"untitled_game/core/normalize"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrInvalidToken is used when a password reset token does not exist, has expired, or has already
+// been used.
+var ErrInvalidToken = errors.New("invalid password reset token")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	GetByEmail(email string) (Account, error)
+	CreateToken(id int, tokenHash string, expiresAt time.Time) error
+	ResetPassword(tokenHash string, password string) (int, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// GetByEmail retrieves an account from the database by its email. If no account has exactly the
+// given email, then the account whose email has the same canonical form is retrieved instead.
+func (r *accountRepository) GetByEmail(email string) (Account, error) {
+	const q = `SELECT id, email FROM accounts WHERE email = $1 OR normalized_email = $2 ORDER BY email = $1 DESC LIMIT 1`
+
+	var account Account
+	if err := r.db.Get(&account, q, email, normalize.Email(email)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, ErrAccountNotFound
+		}
+		return account, err
+	}
+	return account, nil
+}
+
+// CreateToken inserts a new password reset token for the account with the given id.
+func (r *accountRepository) CreateToken(id int, tokenHash string, expiresAt time.Time) error {
+	const q = `INSERT INTO password_resets (account_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+
+	_, err := r.db.Exec(q, id, tokenHash, expiresAt)
+	return err
+}
+
+// ResetPassword consumes a password reset token and sets the password of the account that owns the
+// token. All other outstanding reset tokens for the account are invalidated. The id of the account
+// is returned.
+func (r *accountRepository) ResetPassword(tokenHash string, password string) (int, error) {
+	const (
+		qUseToken       = `UPDATE password_resets SET used_at = now() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() RETURNING account_id`
+		qSetPassword    = `UPDATE accounts SET password = $2 WHERE id = $1`
+		qInvalidateRest = `UPDATE password_resets SET used_at = now() WHERE account_id = $1 AND used_at IS NULL`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	if err := tx.Get(&id, qUseToken, tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidToken
+		}
+		return 0, err
+	}
+
+	if _, err := tx.Exec(qSetPassword, id, password); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(qInvalidateRest, id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
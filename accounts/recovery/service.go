@@ -0,0 +1,128 @@
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/session"
+	"untitled_game/core/hasher"
+	"untitled_game/core/mail"
+	"untitled_game/core/token"
+)
+
+// resetEmail is the body of the email that is sent when a password reset is requested.
+const resetEmail = `A password reset was requested for your Untitled Game account.
+
+To choose a new password, visit the link below:
+
+%s
+
+This link expires in %d minutes and can only be used once. If you did not request a password
+reset, you can safely ignore this email.
+`
+
+// Service provides account recovery related services.
+type Service interface {
+	RequestReset(email string) error
+	ResetPassword(token string, password string, meta session.Metadata) error
+}
+
+type sessionStore interface {
+	RemoveAll(sess session.Session) error
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+// ServiceConfig represents configuration options for an account recovery service.
+type ServiceConfig struct {
+	LinkURL  string
+	TokenTTL time.Duration
+}
+
+type service struct {
+	sess     sessionStore
+	accounts AccountRepository
+	hasher   hasher.Hasher
+	mailer   mail.Mailer
+	audit    auditor
+	linkURL  string
+	tokenTTL time.Duration
+}
+
+// NewService creates a new account recovery service. The mailer should deliver messages in the
+// background, so that the time it takes to request a reset does not reveal whether an email was sent.
+func NewService(sess sessionStore, accounts AccountRepository, hasher hasher.Hasher, mailer mail.Mailer, audit auditor, cfg ServiceConfig) Service {
+	return &service{
+		sess:     sess,
+		accounts: accounts,
+		hasher:   hasher,
+		mailer:   mailer,
+		audit:    audit,
+		linkURL:  cfg.LinkURL,
+		tokenTTL: cfg.TokenTTL,
+	}
+}
+
+// RequestReset issues a single-use password reset token for the account with the given email and
+// emails a reset link to the account email address. If no account exists with the given email, then
+// no error is returned so that callers cannot determine which email addresses are registered.
+func (s *service) RequestReset(email string) error {
+	account, err := s.accounts.GetByEmail(strings.ToLower(email))
+	if err != nil {
+		if errors.Is(err, ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	t, err := token.Generate(32)
+	if err != nil {
+		return err
+	}
+
+	if err := s.accounts.CreateToken(account.ID, token.Hash(t), time.Now().Add(s.tokenTTL)); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf(resetEmail, s.linkURL+"?token="+url.QueryEscape(t), int(s.tokenTTL.Minutes())),
+	})
+}
+
+// ResetPassword consumes a password reset token and sets a new password for the account that owns
+// the token. All existing sessions for the account are revoked, and the password change is recorded
+// in the audit log.
+func (s *service) ResetPassword(t string, password string, meta session.Metadata) error {
+	hashedPw, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.accounts.ResetPassword(token.Hash(t), hashedPw)
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(audit.Entry{
+		Type:      audit.EventPasswordChanged,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   passwordChange{"reset"},
+	})
+	return s.sess.RemoveAll(session.Session{ID: id})
+}
+
+// passwordChange represents the payload of a password change audit event.
+type passwordChange struct {
+	Method string `json:"method"`
+}
@@ -1,20 +1,28 @@
 package register
 
 import (
+	"untitled_game/core/hasher"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
 
-// NewAccount represents the data required to register a new account.
+// NewAccount represents the data required to register a new account. The invite code is required
+// while registration is invite-only, and is otherwise optional. The captcha response is required when
+// bot verification is enabled for registration and the client has crossed its activity threshold.
 type NewAccount struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
+	Captcha    string `json:"captcha"`
 }
 
 // Validate validates new account data.
 func (a NewAccount) Validate() error {
 	return validation.ValidateStruct(&a,
 		validation.Field(&a.Email, validation.Required, is.Email),
-		validation.Field(&a.Password, validation.Required),
+		validation.Field(&a.Password, hasher.PasswordRules...),
+		validation.Field(&a.InviteCode, validation.RuneLength(0, 64)),
+		validation.Field(&a.Captcha, validation.RuneLength(0, 4096)),
 	)
 }
@@ -1,7 +1,10 @@
 package register
 
 import (
+	"database/sql"
 	"errors"
+	"untitled_game/accounts/invite"
+	"untitled_game/core/normalize"
 	"untitled_game/core/postgres"
 
 	"github.com/jmoiron/sqlx"
@@ -11,9 +14,24 @@ import (
 // already being in use.
 var ErrAccountExists = errors.New("account already exists")
 
+// ErrInvalidInvite is used when an invite code does not exist, has expired, has been revoked, or has
+// already been used the maximum number of times.
+var ErrInvalidInvite = errors.New("invalid invite code")
+
 // AccountRepository provides methods for interacting with an account store.
 type AccountRepository interface {
-	Create(account NewAccount, token string) error
+	Create(account NewAccount, tokenHash string) (int, error)
+	Normalize() (int, error)
+}
+
+// normalizeBatchSize is the number of accounts that are read at a time when normalizing emails.
+const normalizeBatchSize = 1000
+
+// storedEmail represents the email address of an account along with its stored canonical form.
+type storedEmail struct {
+	ID         int            `db:"id"`
+	Email      string         `db:"email"`
+	Normalized sql.NullString `db:"normalized_email"`
 }
 
 type accountRepository struct {
@@ -25,15 +43,88 @@ func NewAccountRepository(db *sqlx.DB) AccountRepository {
 	return &accountRepository{db}
 }
 
-// Creates inserts a new account into the database.
-func (r *accountRepository) Create(account NewAccount, token string) error {
-	const q = `INSERT INTO accounts (email, password, verification_token, verification_token_expires_at) VALUES ($1, $2, $3, now() + interval '1 day')`
+// Create inserts a new account into the database along with the canonical form of its email and the
+// hash of its verification token, and returns the id of the new account. ErrAccountExists is returned
+// if another account has the same email or the same canonical email. If the account has an invite
+// code, then the code is claimed before the account is inserted and redeemed in the same
+// transaction, so that a code is only used up if the account is created, and ErrInvalidInvite is
+// returned for an invalid code whether or not the email is in use.
+func (r *accountRepository) Create(account NewAccount, tokenHash string) (int, error) {
+	const q = `INSERT INTO accounts (email, normalized_email, password, verification_token, verification_token_expires_at) VALUES ($1, $2, $3, $4, now() + interval '1 day') RETURNING id`
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
-	if _, err := r.db.Exec(q, account.Email, account.Password, token); err != nil {
+	if account.InviteCode != "" {
+		if err := invite.Claim(tx, account.InviteCode); err != nil {
+			if errors.Is(err, invite.ErrInvalidCode) {
+				return 0, ErrInvalidInvite
+			}
+			return 0, err
+		}
+	}
+
+	var id int
+	if err := tx.Get(&id, q, account.Email, normalize.Email(account.Email), account.Password, tokenHash); err != nil {
 		if postgres.IsUniqueViolationError(err) {
-			return ErrAccountExists
+			return 0, ErrAccountExists
+		}
+		return 0, err
+	}
+
+	if account.InviteCode != "" {
+		if err := invite.Redeem(tx, account.InviteCode, id); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// Normalize recomputes the canonical email of every account whose stored canonical email differs
+// from the one produced by the normalize package, and returns the number of updated accounts. This
+// covers accounts that were backfilled by a migration or normalized by older rules. Only emails
+// that contain non-ASCII characters are checked, since the backfill migration applies the same rules
+// as the normalize package to ASCII emails. Accounts are read in batches in id order, and an account
+// whose canonical email is already taken by another account keeps its stored canonical email.
+func (r *accountRepository) Normalize() (int, error) {
+	const (
+		qList = `SELECT id, email, normalized_email FROM accounts WHERE id > $1 AND email IS NOT NULL AND email !~ '^[[:ascii:]]*$' ORDER BY id LIMIT $2`
+		qSet  = `UPDATE accounts SET normalized_email = $3 WHERE id = $1 AND email = $2`
+	)
+
+	n, lastID := 0, 0
+	for {
+		emails := []storedEmail{}
+		if err := r.db.Select(&emails, qList, lastID, normalizeBatchSize); err != nil {
+			return n, err
+		}
+		if len(emails) == 0 {
+			return n, nil
+		}
+		lastID = emails[len(emails)-1].ID
+
+		for _, e := range emails {
+			normalized := normalize.Email(e.Email)
+			if e.Normalized.Valid && e.Normalized.String == normalized {
+				continue
+			}
+
+			res, err := r.db.Exec(qSet, e.ID, e.Email, normalized)
+			if err != nil {
+				if postgres.IsUniqueViolationError(err) {
+					continue
+				}
+				return n, err
+			}
+
+			updated, err := res.RowsAffected()
+			if err != nil {
+				return n, err
+			}
+			n += int(updated)
 		}
-		return err
 	}
-	return nil
 }
@@ -1,40 +1,153 @@
 package register
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/invite"
+	"untitled_game/accounts/session"
+	"untitled_game/core/hasher"
+	"untitled_game/core/mail"
 	"untitled_game/core/token"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
+// captchaEndpoint is the name of the bot verification endpoint that registrations are checked
+// against.
+const captchaEndpoint = "register"
+
+// existingAccountEmail is the body of the email that is sent when a registration is attempted with
+// an email address that is already in use.
+const existingAccountEmail = `Someone tried to create a new Untitled Game account using this email address, but you
+already have an account.
+
+If this was you, you can log in with your existing account. If you have forgotten your password,
+you can reset it here:
+
+%s
+
+If this was not you, you can safely ignore this email.
+`
+
 // Service provides account registration related services.
 type Service interface {
-	CreateAccount(account NewAccount) error
+	CreateAccount(account NewAccount, meta session.Metadata) error
+}
+
+// verifier sends the email verification token of a newly created account to its owner.
+type verifier interface {
+	Send(email string, token string) error
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+// botGuard checks whether a request must pass bot verification and verifies its captcha response.
+type botGuard interface {
+	Check(endpoint string, response string, ip string) error
+}
+
+// ServiceConfig represents configuration options for an account registration service. When
+// enumeration protection is enabled, registering with an email address that is already in use does
+// not return an error. The owner of the email address is notified instead, and the reset url is
+// included in the notice so that they can recover their existing account. When invites are
+// required, accounts can only be created with a valid invite code.
+type ServiceConfig struct {
+	ProtectEnumeration bool
+	ResetURL           string
+	RequireInvite      bool
 }
 
 type service struct {
 	accounts AccountRepository
+	hasher   hasher.Hasher
+	verifier verifier
+	mailer   mail.Mailer
+	audit    auditor
+	bots     botGuard
+	protect  bool
+	resetURL string
+	invite   bool
 }
 
-// NewService creates a new account registration service.
-func NewService(accounts AccountRepository) Service {
-	return &service{accounts}
+// NewService creates a new account registration service. The verifier and the mailer should deliver
+// messages in the background, so that the response to a registration does not depend on whether an
+// email was sent, and a failed delivery does not fail a registration that has already been stored.
+func NewService(accounts AccountRepository, hasher hasher.Hasher, verifier verifier, mailer mail.Mailer, audit auditor, bots botGuard, cfg ServiceConfig) Service {
+	return &service{
+		accounts: accounts,
+		hasher:   hasher,
+		verifier: verifier,
+		mailer:   mailer,
+		audit:    audit,
+		bots:     bots,
+		protect:  cfg.ProtectEnumeration,
+		resetURL: cfg.ResetURL,
+		invite:   cfg.RequireInvite,
+	}
 }
 
-// CreateAccount creates a new account.
-func (s *service) CreateAccount(account NewAccount) error {
-	hashedPw, err := bcrypt.GenerateFromPassword([]byte(account.Password), bcrypt.DefaultCost)
+// CreateAccount creates a new account and sends a verification email to the account email address.
+// If bot verification is required for the client, then captcha.ErrRequired or captcha.ErrFailed is
+// returned unless the captcha response is valid. If an invite code is supplied, then it is redeemed
+// for the new account. If invites are required and no invite code is supplied, then
+// ErrInvalidInvite is returned. The registration is recorded in the audit log along with the client
+// that made it.
+func (s *service) CreateAccount(account NewAccount, meta session.Metadata) error {
+	if err := s.bots.Check(captchaEndpoint, account.Captcha, meta.IP); err != nil {
+		return err
+	}
+
+	account.InviteCode = invite.Normalize(account.InviteCode)
+	if s.invite && account.InviteCode == "" {
+		return ErrInvalidInvite
+	}
+
+	hashedPw, err := s.hasher.Hash(account.Password)
 	if err != nil {
 		return err
 	}
 
-	token, err := token.Generate(32)
+	t, err := token.Generate(32)
 	if err != nil {
 		return err
 	}
 
 	account.Email = strings.ToLower(account.Email)
-	account.Password = string(hashedPw)
+	account.Password = hashedPw
+
+	id, err := s.accounts.Create(account, token.Hash(t))
+	if err != nil {
+		if errors.Is(err, ErrAccountExists) && s.protect {
+			return s.notifyExisting(account.Email)
+		}
+		return err
+	}
+
+	s.audit.Record(audit.Entry{
+		Type:      audit.EventRegistered,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   registration{account.InviteCode},
+	})
+	return s.verifier.Send(account.Email, t)
+}
+
+// registration represents the payload of a registration audit event.
+type registration struct {
+	InviteCode string `json:"invite_code,omitempty"`
+}
 
-	return s.accounts.Create(account, token)
+// notifyExisting emails the owner of an existing account to let them know that a registration was
+// attempted with their email address.
+func (s *service) notifyExisting(email string) error {
+	return s.mailer.Send(mail.Message{
+		To:      email,
+		Subject: "You already have an account",
+		Body:    fmt.Sprintf(existingAccountEmail, s.resetURL),
+	})
 }
@@ -0,0 +1,66 @@
+package role
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Roles that can be assigned to accounts. The player role is granted to every account implicitly and
+// cannot be assigned or removed.
+const (
+	Player    = "player"
+	Moderator = "moderator"
+	Support   = "support"
+	Admin     = "admin"
+)
+
+// ranks orders roles by privilege. The player role has no privilege and is not listed.
+var ranks = map[string]int{
+	Moderator: 1,
+	Support:   1,
+	Admin:     2,
+}
+
+// Permissions that are granted by roles.
+const (
+	PermPlay             = "game.play"
+	PermReadAccounts     = "accounts.read"
+	PermBanAccounts      = "accounts.ban"
+	PermLogoutAccounts   = "accounts.logout"
+	PermVerifyAccounts   = "accounts.verify"
+	PermResetPasswords   = "accounts.reset_password"
+	PermUnlockAccounts   = "accounts.unlock"
+	PermReadDisplayNames = "display_names.read"
+	PermManageRoles      = "roles.manage"
+	PermReadAudit        = "audit.read"
+	PermManageInvites    = "invites.manage"
+)
+
+// Assignment represents the roles that are assigned to an account.
+type Assignment struct {
+	Roles []string `json:"roles"`
+}
+
+// Validate validates role assignment data.
+func (a Assignment) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Roles, validation.NotNil, validation.Each(validation.In(Moderator, Support, Admin))),
+	)
+}
+
+// Roles represents the roles of an account and the permissions that they grant.
+type Roles struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// Rank returns the rank of the most privileged of the roles. Accounts with a higher rank hold more
+// privileged roles.
+func (r Roles) Rank() int {
+	rank := 0
+	for _, name := range r.Roles {
+		if ranks[name] > rank {
+			rank = ranks[name]
+		}
+	}
+	return rank
+}
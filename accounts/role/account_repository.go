@@ -0,0 +1,107 @@
+package role
+
+import (
+	"database/sql"
+	"errors"
+	"untitled_game/core/normalize"
+	"untitled_game/core/postgres"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Roles(id int) ([]string, error)
+	Permissions(id int) ([]string, error)
+	SetRoles(id int, roles []string) error
+	GrantByEmail(email string, role string) (int, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Roles retrieves the roles that are assigned to the account with the given id. The implicit player
+// role is not included.
+func (r *accountRepository) Roles(id int) ([]string, error) {
+	const q = `SELECT role FROM account_roles WHERE account_id = $1 ORDER BY role`
+
+	roles := []string{}
+	if err := r.db.Select(&roles, q, id); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Permissions retrieves the permissions that are granted to the account with the given id by its
+// roles, including the implicit player role.
+func (r *accountRepository) Permissions(id int) ([]string, error) {
+	const q = `SELECT DISTINCT permission FROM role_permissions
+		WHERE role = 'player' OR role IN (SELECT role FROM account_roles WHERE account_id = $1)
+		ORDER BY permission`
+
+	permissions := []string{}
+	if err := r.db.Select(&permissions, q, id); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// SetRoles replaces the roles that are assigned to the account with the given id.
+func (r *accountRepository) SetRoles(id int, roles []string) error {
+	const (
+		qDelete = `DELETE FROM account_roles WHERE account_id = $1`
+		qInsert = `INSERT INTO account_roles (account_id, role) VALUES ($1, $2)`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(qDelete, id); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if _, err := tx.Exec(qInsert, id, role); err != nil {
+			if postgres.IsForeignKeyViolationError(err) {
+				return ErrAccountNotFound
+			}
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GrantByEmail assigns a role to the account with the given email, if it does not already have it,
+// and returns the id of the account. The account is matched by its canonical email, and only
+// verified accounts that have not been deleted are granted the role, so that the role cannot be
+// claimed by registering an email address that its owner has not registered yet.
+func (r *accountRepository) GrantByEmail(email string, role string) (int, error) {
+	const (
+		qGet   = `SELECT id FROM accounts WHERE normalized_email = $1 AND verified_at IS NOT NULL AND deleted_at IS NULL`
+		qGrant = `INSERT INTO account_roles (account_id, role) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	)
+
+	var id int
+	if err := r.db.Get(&id, qGet, normalize.Email(email)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrAccountNotFound
+		}
+		return 0, err
+	}
+
+	if _, err := r.db.Exec(qGrant, id, role); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
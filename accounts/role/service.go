@@ -0,0 +1,90 @@
+package role
+
+import (
+	"fmt"
+)
+
+// Service provides role and permission related services.
+type Service interface {
+	Get(id int) (Roles, error)
+	Permissions(id int) ([]string, error)
+	Set(id int, roles []string) (Roles, error)
+	Bootstrap(email string) error
+}
+
+type sessionStore interface {
+	SetPermissions(id int, permissions []string) error
+}
+
+type service struct {
+	sess     sessionStore
+	accounts AccountRepository
+}
+
+// NewService creates a new role service.
+func NewService(sess sessionStore, accounts AccountRepository) Service {
+	return &service{sess, accounts}
+}
+
+// Get retrieves the roles of the account with the given id and the permissions that they grant.
+func (s *service) Get(id int) (Roles, error) {
+	roles, err := s.accounts.Roles(id)
+	if err != nil {
+		return Roles{}, err
+	}
+
+	permissions, err := s.accounts.Permissions(id)
+	if err != nil {
+		return Roles{}, err
+	}
+	return Roles{roles, permissions}, nil
+}
+
+// Permissions retrieves the permissions that are granted to the account with the given id.
+func (s *service) Permissions(id int) ([]string, error) {
+	return s.accounts.Permissions(id)
+}
+
+// Set replaces the roles of the account with the given id. Roles that are listed more than once are
+// only assigned once. The permissions cached with the sessions of the account are updated so that
+// the change takes effect immediately.
+func (s *service) Set(id int, roles []string) (Roles, error) {
+	unique := make([]string, 0, len(roles))
+	seen := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		if !seen[role] {
+			seen[role] = true
+			unique = append(unique, role)
+		}
+	}
+
+	if err := s.accounts.SetRoles(id, unique); err != nil {
+		return Roles{}, err
+	}
+
+	r, err := s.Get(id)
+	if err != nil {
+		return Roles{}, err
+	}
+
+	if err := s.sess.SetPermissions(id, r.Permissions); err != nil {
+		return Roles{}, err
+	}
+	return r, nil
+}
+
+// Bootstrap grants the admin role to the verified account with the given email. This allows the
+// first admins to be created before anyone is able to assign roles. ErrAccountNotFound is returned
+// if no verified account has the email.
+func (s *service) Bootstrap(email string) error {
+	id, err := s.accounts.GrantByEmail(email, Admin)
+	if err != nil {
+		return fmt.Errorf("grant admin role to %s: %w", email, err)
+	}
+
+	permissions, err := s.accounts.Permissions(id)
+	if err != nil {
+		return err
+	}
+	return s.sess.SetPermissions(id, permissions)
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"errors"
+	"untitled_game/core/token"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// challengesPrefix is used to prefix redis keys that represent pending login challenges.
+const challengesPrefix = "challenges:"
+
+// maxChallengeAttempts is the maximum number of times a login challenge can be attempted before it
+// is discarded.
+const maxChallengeAttempts = 5
+
+// cmdGetChallenge attempts to retrieve a login challenge from redis. Each retrieval counts as an
+// attempt, and the challenge is deleted once the maximum number of attempts has been exceeded.
+var cmdGetChallenge = redis.NewScript(1, `
+	local id = redis.call('HGET', KEYS[1], 'id')
+	if not id then
+		return nil
+	end
+	local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+	if attempts > tonumber(ARGV[1]) then
+		redis.call('DEL', KEYS[1])
+		return nil
+	end
+	return {id, redis.call('HGET', KEYS[1], 'device')}
+`)
+
+// ErrChallengeNotFound is used when a login challenge does not exist, has expired, or has been
+// attempted too many times.
+var ErrChallengeNotFound = errors.New("challenge not found")
+
+// Challenge represents a pending login that must be completed with a second authentication factor
+// before a session is created.
+type Challenge struct {
+	ID     int
+	Device string
+}
+
+// AddChallenge adds a new login challenge to the store and returns the challenge token.
+func (s *store) AddChallenge(c Challenge) (string, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	t, err := token.Generate(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return "", err
+	}
+	if err := conn.Send("HSET", challengesPrefix+t, "id", c.ID, "device", c.Device, "attempts", 0); err != nil {
+		return "", err
+	}
+	if err := conn.Send("EXPIRE", challengesPrefix+t, int(s.challengeTTL.Seconds())); err != nil {
+		return "", err
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return "", err
+	}
+	return t, nil
+}
+
+// GetChallenge retrieves a login challenge from the store.
+func (s *store) GetChallenge(t string) (Challenge, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	res, err := redis.Values(cmdGetChallenge.Do(conn, challengesPrefix+t, maxChallengeAttempts))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return Challenge{}, ErrChallengeNotFound
+		}
+		return Challenge{}, err
+	}
+
+	var c Challenge
+	if _, err := redis.Scan(res, &c.ID, &c.Device); err != nil {
+		return Challenge{}, err
+	}
+	return c, nil
+}
+
+// RemoveChallenge removes a login challenge from the store.
+func (s *store) RemoveChallenge(t string) error {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", challengesPrefix+t)
+	return err
+}
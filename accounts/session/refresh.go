@@ -0,0 +1,106 @@
+package session
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// refreshPrefix is used to prefix redis keys that map a refresh key to the key of its session. The
+// full key has the form refreshPrefix + "<user id>:<refresh key>". Refresh keys that have been
+// rotated out remain mapped to their session until they expire so that reuse can be detected.
+const refreshPrefix = "refresh:"
+
+// Results of the refresh script.
+const (
+	refreshInvalid = 0
+	refreshOK      = 1
+	refreshReused  = 2
+)
+
+// cmdRefreshSession exchanges a refresh key for a new access key and refresh key. If the refresh key
+// has already been exchanged, then it has been replayed and the session is removed along with every
+// key that was issued for it. Otherwise, the previous access key is removed and the expiration time of
+// the session is extended.
+var cmdRefreshSession = redis.NewScript(2, luaRemoveSession+`
+	local key = redis.call('GET', KEYS[2])
+	if not key then
+		return 0
+	end
+	redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[5])
+	if not redis.call('ZSCORE', KEYS[1], key) then
+		return 0
+	end
+	local meta = ARGV[2] .. key
+	if redis.call('HGET', meta, 'refresh') ~= ARGV[1] then
+		removeSession(KEYS[1], ARGV[2], ARGV[3], ARGV[4], key)
+		return 2
+	end
+	local access = redis.call('HGET', meta, 'access')
+	if access then
+		redis.call('DEL', ARGV[3] .. access)
+	end
+	redis.call('SET', ARGV[3] .. ARGV[6], key, 'EX', ARGV[9])
+	redis.call('SET', ARGV[4] .. ARGV[7], key, 'EX', ARGV[10])
+	redis.call('HSET', meta, 'access', ARGV[6], 'refresh', ARGV[7], 'last_seen_at', ARGV[5])
+	redis.call('EXPIRE', meta, ARGV[10])
+	redis.call('ZADD', KEYS[1], ARGV[8], key)
+	redis.call('EXPIRE', KEYS[1], ARGV[11])
+	return 1
+`)
+
+// ErrInvalidRefreshToken is used when a refresh token does not exist or its session has expired.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused is used when a refresh token that has already been exchanged is supplied
+// again. The session that the token was issued for is removed, since either the token or its
+// replacement is held by someone other than the user.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// Refresh exchanges a refresh token for new auth tokens. The key of the supplied session is a refresh
+// key. The session that the refresh key was issued for keeps its key, and its expiration time is
+// extended.
+func (s *store) Refresh(sess Session) (Token, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	access, refresh, err := generateKeys()
+	if err != nil {
+		return Token{}, err
+	}
+
+	now := time.Now()
+
+	res, err := redis.Int(cmdRefreshSession.Do(conn,
+		sessionsKey(sess.ID), refreshKey(sess),
+		sess.Key, metadataKeyPrefix(sess.ID), accessKeyPrefix(sess.ID), refreshKeyPrefix(sess.ID),
+		now.Unix(), access, refresh, now.Add(s.sessionTTL).Unix(),
+		int(s.accessTTL.Seconds()), int(s.sessionTTL.Seconds()), int(s.userTTL.Seconds()),
+	))
+	if err != nil {
+		return Token{}, err
+	}
+
+	switch res {
+	case refreshOK:
+		return newToken(sess.ID, access, refresh, s.accessTTL), nil
+	case refreshReused:
+		return Token{}, ErrRefreshTokenReused
+	default:
+		return Token{}, ErrInvalidRefreshToken
+	}
+}
+
+// refreshKeyPrefix returns the common prefix of the redis keys that map refresh keys to sessions for
+// a user.
+func refreshKeyPrefix(id int) string {
+	return refreshPrefix + strconv.Itoa(id) + tokenDelimiter
+}
+
+// refreshKey returns the redis key that maps a refresh key to the key of its session. The key of the
+// supplied session is the refresh key.
+func refreshKey(sess Session) string {
+	return refreshKeyPrefix(sess.ID) + sess.Key
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 	"untitled_game/core/token"
 )
 
@@ -15,10 +16,13 @@ var ErrInvalidAuthToken = errors.New("invalid auth token")
 // tokenDelimiter is used to separate the user id and session key in the auth token.
 const tokenDelimiter = ":"
 
-// Session represents a user session.
+// Session represents a user session. The key identifies the session for as long as it exists, while
+// the access and refresh tokens that are issued for the session are rotated. The permissions of the
+// account are cached with the session so that requests can be authorized without a database query.
 type Session struct {
-	ID  int
-	Key string
+	ID          int
+	Key         string
+	Permissions []string
 }
 
 // New creates a new session for account with the given id.
@@ -27,20 +31,64 @@ func New(id int) (Session, error) {
 	if err != nil {
 		return Session{}, err
 	}
-	return Session{id, key}, nil
+	return Session{ID: id, Key: key}, nil
 }
 
-// Token represents an auth token that is created following a successful authentication attempt.
+// HasPermission returns true if the session has the given permission.
+func (s Session) HasPermission(permission string) bool {
+	for _, p := range s.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Metadata represents information about the client that created a session. The device name is
+// supplied by the client so that the user can recognize the session.
+type Metadata struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+// Info represents an active session along with its metadata.
+type Info struct {
+	Key        string    `json:"key"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// Token represents the auth tokens that are created following a successful authentication attempt.
+// The access token authenticates requests and expires after a short time. The refresh token is
+// exchanged for a new pair of tokens once the access token has expired, and is rotated on every
+// exchange.
 type Token struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// newToken creates auth tokens for the user with the given id from an access key and a refresh key.
+func newToken(id int, accessKey string, refreshKey string, accessTTL time.Duration) Token {
+	return Token{
+		Token:        formatToken(id, accessKey),
+		RefreshToken: formatToken(id, refreshKey),
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}
 }
 
-// CreateToken creates an auth token from a session.
-func CreateToken(sess Session) Token {
-	return Token{strconv.Itoa(sess.ID) + tokenDelimiter + sess.Key}
+// formatToken formats a user id and a key as an auth token.
+func formatToken(id int, key string) string {
+	return strconv.Itoa(id) + tokenDelimiter + key
 }
 
-// ParseToken parses an auth token into a session.
+// ParseToken parses an auth token into a session. The session key is the access or refresh key that
+// is carried by the token, which the session store resolves to the key of the session.
 func ParseToken(token string) (Session, error) {
 	parts := strings.SplitN(token, tokenDelimiter, 2)
 	if len(parts) != 2 {
@@ -51,7 +99,7 @@ func ParseToken(token string) (Session, error) {
 	if err != nil {
 		return Session{}, ErrInvalidAuthToken
 	}
-	return Session{id, parts[1]}, nil
+	return Session{ID: id, Key: parts[1]}, nil
 }
 
 type contextKey int
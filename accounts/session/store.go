@@ -3,7 +3,9 @@ package session
 import (
 	"errors"
 	"strconv"
+	"strings"
 	"time"
+	"untitled_game/core/token"
 
 	"github.com/garyburd/redigo/redis"
 )
@@ -11,45 +13,146 @@ import (
 // sessionsPrefix is used to prefix redis keys that represent user sessions.
 const sessionsPrefix = "sessions:"
 
-// cmdGetSession attempts to retrieve a session from redis. Before querying for the session, the
-// expired sessions are removed. If the session is found, then the expiration time of the
-// individual session as well as the set containing all of the user's sessions is reset.
-var cmdGetSession = redis.NewScript(1, `
+// metadataPrefix is used to prefix redis keys that hold the metadata of an individual session. The
+// full key has the form metadataPrefix + "<user id>:<session key>".
+const metadataPrefix = "session:"
+
+// accessPrefix is used to prefix redis keys that map an access key to the key of its session. The
+// full key has the form accessPrefix + "<user id>:<access key>".
+const accessPrefix = "access:"
+
+// luaRemoveSession defines a lua function that removes a session along with its metadata and its
+// current access and refresh keys. It returns the number of sessions that were removed.
+const luaRemoveSession = `
+	local function removeSession(sessions, metaPrefix, accessPrefix, refreshPrefix, key)
+		local tokens = redis.call('HMGET', metaPrefix .. key, 'access', 'refresh')
+		if tokens[1] then
+			redis.call('DEL', accessPrefix .. tokens[1])
+		end
+		if tokens[2] then
+			redis.call('DEL', refreshPrefix .. tokens[2])
+		end
+		redis.call('DEL', metaPrefix .. key)
+		return redis.call('ZREM', sessions, key)
+	end
+`
+
+// cmdGetSession attempts to resolve an access key to the key of its session. Before querying for the
+// session, the expired sessions are removed. If the session is found, then the last seen time of the
+// session is updated and the session key is returned along with the cached permissions.
+var cmdGetSession = redis.NewScript(2, `
+	local key = redis.call('GET', KEYS[2])
+	if not key then
+		return nil
+	end
 	redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
-	local res = redis.call('ZSCORE', KEYS[1], ARGV[2])
-	if res == nil then
+	if not redis.call('ZSCORE', KEYS[1], key) then
 		return nil
 	end
-	redis.call('ZREM', KEYS[1], ARGV[2])
-	redis.call('ZADD' KEYS[1], ARGV[3], ARGV[2])
-	redis.call('EXPIRE', KEYS[1], ARGV[4])
+	redis.call('HSET', ARGV[2] .. key, 'last_seen_at', ARGV[1])
+	return {key, redis.call('HGET', ARGV[2] .. key, 'permissions') or ''}
+`)
+
+// cmdPeekSession attempts to resolve an access key to the key of its session without changing the
+// store. Sessions that have expired but have not been removed yet are treated as missing. If the
+// session is found, then the session key is returned along with the cached permissions.
+var cmdPeekSession = redis.NewScript(2, `
+	local key = redis.call('GET', KEYS[2])
+	if not key then
+		return nil
+	end
+	local expiry = redis.call('ZSCORE', KEYS[1], key)
+	if not expiry or tonumber(expiry) <= tonumber(ARGV[1]) then
+		return nil
+	end
+	return {key, redis.call('HGET', ARGV[2] .. key, 'permissions') or ''}
+`)
+
+// cmdListSessions retrieves all of a user's unexpired sessions along with their metadata. Expired
+// sessions are removed before listing.
+var cmdListSessions = redis.NewScript(1, `
+	redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+	local keys = redis.call('ZRANGE', KEYS[1], 0, -1)
+	local res = {}
+	for i, key in ipairs(keys) do
+		res[i] = {key, redis.call('HGETALL', ARGV[2] .. key)}
+	end
 	return res
 `)
 
+// cmdSetPermissions replaces the cached permissions of all of a user's sessions.
+var cmdSetPermissions = redis.NewScript(1, `
+	local keys = redis.call('ZRANGE', KEYS[1], 0, -1)
+	for _, key in ipairs(keys) do
+		if redis.call('EXISTS', ARGV[1] .. key) == 1 then
+			redis.call('HSET', ARGV[1] .. key, 'permissions', ARGV[2])
+		end
+	end
+`)
+
+// cmdRemoveSession removes a single session of a user.
+var cmdRemoveSession = redis.NewScript(1, luaRemoveSession+`
+	return removeSession(KEYS[1], ARGV[1], ARGV[2], ARGV[3], ARGV[4])
+`)
+
+// cmdRemoveAllSessions removes all of a user's sessions.
+var cmdRemoveAllSessions = redis.NewScript(1, luaRemoveSession+`
+	local keys = redis.call('ZRANGE', KEYS[1], 0, -1)
+	for _, key in ipairs(keys) do
+		removeSession(KEYS[1], ARGV[1], ARGV[2], ARGV[3], key)
+	end
+	redis.call('DEL', KEYS[1])
+`)
+
+// cmdRemoveOtherSessions removes all of a user's sessions except for the session with the provided
+// key.
+var cmdRemoveOtherSessions = redis.NewScript(1, luaRemoveSession+`
+	local keys = redis.call('ZRANGE', KEYS[1], 0, -1)
+	for _, key in ipairs(keys) do
+		if key ~= ARGV[4] then
+			removeSession(KEYS[1], ARGV[1], ARGV[2], ARGV[3], key)
+		end
+	end
+`)
+
 // ErrSessionNotFound is used when attempting to retrieve a token that does not exist in the store.
 var ErrSessionNotFound = errors.New("session not found")
 
 // Store provides methods for interacting with a session store.
 type Store interface {
 	Get(sess Session) (Session, error)
-	Add(sess Session) error
+	Peek(sess Session) (Session, error)
+	Add(sess Session, meta Metadata) (Token, error)
+	Refresh(sess Session) (Token, error)
+	Expiry(sess Session) (time.Time, error)
+	SetPermissions(id int, permissions []string) error
+	List(id int) ([]Info, error)
 	Remove(sess Session) error
 	RemoveAll(sess Session) error
 	RemoveOthers(sess Session) error
+	AddChallenge(c Challenge) (string, error)
+	GetChallenge(token string) (Challenge, error)
+	RemoveChallenge(token string) error
 	Close() error
 }
 
-// StoreConfig represents configuration options for a redis session store.
+// StoreConfig represents configuration options for a redis session store. Access tokens expire
+// after the access ttl. Sessions expire after the session ttl, which is extended every time the
+// session is refreshed.
 type StoreConfig struct {
-	Redis      string
-	SessionTTL time.Duration
-	UserTTL    time.Duration
+	Redis        string
+	AccessTTL    time.Duration
+	SessionTTL   time.Duration
+	UserTTL      time.Duration
+	ChallengeTTL time.Duration
 }
 
 type store struct {
-	redis      *redis.Pool
-	sessionTTL time.Duration
-	userTTL    time.Duration
+	redis        *redis.Pool
+	accessTTL    time.Duration
+	sessionTTL   time.Duration
+	userTTL      time.Duration
+	challengeTTL time.Duration
 }
 
 // NewStore creates a new redis session store.
@@ -69,46 +172,160 @@ func NewStore(cfg StoreConfig) (Store, error) {
 	}
 
 	s := &store{
-		redis:      r,
-		sessionTTL: cfg.SessionTTL,
-		userTTL:    cfg.UserTTL,
+		redis:        r,
+		accessTTL:    cfg.AccessTTL,
+		sessionTTL:   cfg.SessionTTL,
+		userTTL:      cfg.UserTTL,
+		challengeTTL: cfg.ChallengeTTL,
 	}
 	return s, nil
 }
 
-// Get retrieves a session from the store.
+// Get retrieves a session from the store. The key of the supplied session is an access key, which is
+// resolved to the key of the session that it was issued for.
 func (s *store) Get(sess Session) (Session, error) {
 	conn := s.redis.Get()
 	defer conn.Close()
 
-	now := time.Now()
+	res, err := redis.Strings(cmdGetSession.Do(conn, sessionsKey(sess.ID), accessKey(sess), time.Now().Unix(), metadataKeyPrefix(sess.ID)))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return Session{}, ErrSessionNotFound
+		}
+		return Session{}, err
+	}
+	return Session{ID: sess.ID, Key: res[0], Permissions: strings.Fields(res[1])}, nil
+}
+
+// Peek retrieves a session from the store like Get, but without updating the last seen time of the
+// session. This is used when a session is looked up on behalf of another service rather than used
+// by its owner.
+func (s *store) Peek(sess Session) (Session, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
 
-	res, err := cmdGetSession.Do(conn, sessionsPrefix+strconv.Itoa(sess.ID), now.Unix(), sess.Key, now.Add(s.sessionTTL).Unix(), s.userTTL.Seconds)
+	res, err := redis.Strings(cmdPeekSession.Do(conn, sessionsKey(sess.ID), accessKey(sess), time.Now().Unix(), metadataKeyPrefix(sess.ID)))
 	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return Session{}, ErrSessionNotFound
+		}
 		return Session{}, err
 	}
-	if res == nil {
-		return Session{}, ErrSessionNotFound
+	return Session{ID: sess.ID, Key: res[0], Permissions: strings.Fields(res[1])}, nil
+}
+
+// Add adds a new session to the store along with its metadata and returns the auth tokens that are
+// issued for the session.
+func (s *store) Add(sess Session, meta Metadata) (Token, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	access, refresh, err := generateKeys()
+	if err != nil {
+		return Token{}, err
+	}
+
+	now := time.Now()
+	key := metadataKey(sess)
+
+	if err := conn.Send("MULTI"); err != nil {
+		return Token{}, err
+	}
+	if err := conn.Send("ZADD", sessionsKey(sess.ID), now.Add(s.sessionTTL).Unix(), sess.Key); err != nil {
+		return Token{}, err
+	}
+	if err := conn.Send("EXPIRE", sessionsKey(sess.ID), int(s.userTTL.Seconds())); err != nil {
+		return Token{}, err
+	}
+	if err := conn.Send("HSET", key, "device", meta.Device, "ip", meta.IP, "user_agent", meta.UserAgent, "created_at", now.Unix(), "last_seen_at", now.Unix(), "access", access, "refresh", refresh, "permissions", strings.Join(sess.Permissions, " ")); err != nil {
+		return Token{}, err
+	}
+	if err := conn.Send("EXPIRE", key, int(s.sessionTTL.Seconds())); err != nil {
+		return Token{}, err
+	}
+	if err := conn.Send("SET", accessKey(Session{ID: sess.ID, Key: access}), sess.Key, "EX", int(s.accessTTL.Seconds())); err != nil {
+		return Token{}, err
+	}
+	if err := conn.Send("SET", refreshKey(Session{ID: sess.ID, Key: refresh}), sess.Key, "EX", int(s.sessionTTL.Seconds())); err != nil {
+		return Token{}, err
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return Token{}, err
+	}
+	return newToken(sess.ID, access, refresh, s.accessTTL), nil
+}
+
+// Expiry retrieves the expiration time of an access key. The key of the supplied session is an
+// access key.
+func (s *store) Expiry(sess Session) (time.Time, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	ttl, err := redis.Int64(conn.Do("PTTL", accessKey(sess)))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ttl < 0 {
+		return time.Time{}, ErrSessionNotFound
 	}
-	return sess, nil
+	return time.Now().Add(time.Duration(ttl) * time.Millisecond), nil
 }
 
-// Add adds a new session to the store.
-func (s *store) Add(sess Session) error {
+// SetPermissions replaces the cached permissions of all sessions of the user with the given id. This
+// is used when the roles of the user change so that the change takes effect immediately.
+func (s *store) SetPermissions(id int, permissions []string) error {
 	conn := s.redis.Get()
 	defer conn.Close()
 
-	_, err := conn.Do("ZADD", sessionsPrefix+strconv.Itoa(sess.ID), time.Now().Add(s.sessionTTL).Unix(), sess.Key)
+	_, err := cmdSetPermissions.Do(conn, sessionsKey(id), metadataKeyPrefix(id), strings.Join(permissions, " "))
 	return err
 }
 
+// List retrieves all active sessions of the user with the given id along with their metadata.
+func (s *store) List(id int) ([]Info, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	res, err := redis.Values(cmdListSessions.Do(conn, sessionsKey(id), time.Now().Unix(), metadataKeyPrefix(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Info, 0, len(res))
+	for _, entry := range res {
+		values, err := redis.Values(entry, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var key string
+		var fields []interface{}
+		if _, err := redis.Scan(values, &key, &fields); err != nil {
+			return nil, err
+		}
+
+		meta, err := redis.StringMap(fields, nil)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, newInfo(key, meta))
+	}
+	return sessions, nil
+}
+
 // Remove removes a user session from the store.
 func (s *store) Remove(sess Session) error {
 	conn := s.redis.Get()
 	defer conn.Close()
 
-	_, err := conn.Do("ZREM", sessionsPrefix+strconv.Itoa(sess.ID), sess.Key)
-	return err
+	removed, err := redis.Int(cmdRemoveSession.Do(conn, sessionsKey(sess.ID), metadataKeyPrefix(sess.ID), accessKeyPrefix(sess.ID), refreshKeyPrefix(sess.ID), sess.Key))
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
 }
 
 // RemoveAll removes all sessions for the given user.
@@ -116,7 +333,7 @@ func (s *store) RemoveAll(sess Session) error {
 	conn := s.redis.Get()
 	defer conn.Close()
 
-	_, err := conn.Do("DEL", sessionsPrefix+strconv.Itoa(sess.ID))
+	_, err := cmdRemoveAllSessions.Do(conn, sessionsKey(sess.ID), metadataKeyPrefix(sess.ID), accessKeyPrefix(sess.ID), refreshKeyPrefix(sess.ID))
 	return err
 }
 
@@ -126,27 +343,74 @@ func (s *store) RemoveOthers(sess Session) error {
 	conn := s.redis.Get()
 	defer conn.Close()
 
-	sessionsKey := sessionsPrefix + strconv.Itoa(sess.ID)
+	_, err := cmdRemoveOtherSessions.Do(conn, sessionsKey(sess.ID), metadataKeyPrefix(sess.ID), accessKeyPrefix(sess.ID), refreshKeyPrefix(sess.ID), sess.Key)
+	return err
+}
 
-	if err := conn.Send("MULTI"); err != nil {
-		return err
-	}
-	if err := conn.Send("DEL", sessionsKey); err != nil {
-		return err
-	}
-	if err := conn.Send("ZADD", sessionsKey, time.Now().Add(s.sessionTTL).Unix(), sess.Key); err != nil {
-		return err
+// Close closes the underlying redis connection.
+func (s *store) Close() error {
+	return s.redis.Close()
+}
+
+// sessionsKey returns the redis key of the sorted set that contains all sessions for a user.
+func sessionsKey(id int) string {
+	return sessionsPrefix + strconv.Itoa(id)
+}
+
+// metadataKeyPrefix returns the common prefix of the redis keys that contain session metadata for
+// a user.
+func metadataKeyPrefix(id int) string {
+	return metadataPrefix + strconv.Itoa(id) + tokenDelimiter
+}
+
+// metadataKey returns the redis key of the hash that contains the metadata of a session.
+func metadataKey(sess Session) string {
+	return metadataKeyPrefix(sess.ID) + sess.Key
+}
+
+// accessKeyPrefix returns the common prefix of the redis keys that map access keys to sessions for
+// a user.
+func accessKeyPrefix(id int) string {
+	return accessPrefix + strconv.Itoa(id) + tokenDelimiter
+}
+
+// accessKey returns the redis key that maps an access key to the key of its session. The key of the
+// supplied session is the access key.
+func accessKey(sess Session) string {
+	return accessKeyPrefix(sess.ID) + sess.Key
+}
+
+// generateKeys generates a new access key and refresh key.
+func generateKeys() (string, string, error) {
+	access, err := token.Generate(32)
+	if err != nil {
+		return "", "", err
 	}
-	if err := conn.Send("EXPIRE", sessionsKey, s.userTTL.Seconds); err != nil {
-		return err
+	refresh, err := token.Generate(32)
+	if err != nil {
+		return "", "", err
 	}
-	if _, err := conn.Do("EXEC"); err != nil {
-		return err
+	return access, refresh, nil
+}
+
+// newInfo creates session info from a session key and the fields of its metadata hash.
+func newInfo(key string, meta map[string]string) Info {
+	return Info{
+		Key:        key,
+		Device:     meta["device"],
+		IP:         meta["ip"],
+		UserAgent:  meta["user_agent"],
+		CreatedAt:  parseUnix(meta["created_at"]),
+		LastSeenAt: parseUnix(meta["last_seen_at"]),
 	}
-	return nil
 }
 
-// Close closes the underlying redis connection.
-func (s *store) Close() error {
-	return s.redis.Close()
+// parseUnix parses a unix timestamp stored in redis. An invalid or missing timestamp results in the
+// zero time.
+func parseUnix(s string) time.Time {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
 }
@@ -0,0 +1,152 @@
+package throttle
+
+import (
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// failuresPrefix is used to prefix redis keys that track failed attempts.
+const failuresPrefix = "failures:"
+
+// cmdRecordFailure increments the failure count of a key. Once the count reaches the backoff
+// threshold, the key is locked for a delay that doubles with each additional failure up to the
+// maximum delay. Once the count reaches the lockout threshold, the key is locked for the lockout
+// duration. The failure count expires after the failure window or the lock, whichever is longer.
+// The lock delay in seconds is returned.
+var cmdRecordFailure = redis.NewScript(1, `
+	local now = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local backoffAfter = tonumber(ARGV[3])
+	local lockoutAfter = tonumber(ARGV[4])
+	local count = redis.call('HINCRBY', KEYS[1], 'count', 1)
+	local delay = 0
+	if lockoutAfter > 0 and count >= lockoutAfter then
+		delay = tonumber(ARGV[7])
+	elseif backoffAfter > 0 and count >= backoffAfter then
+		delay = math.min(tonumber(ARGV[5]) * 2 ^ (count - backoffAfter), tonumber(ARGV[6]))
+	end
+	delay = math.floor(delay)
+	if delay > 0 then
+		redis.call('HSET', KEYS[1], 'locked_until', now + delay)
+	end
+	redis.call('EXPIRE', KEYS[1], math.max(window, delay))
+	return delay
+`)
+
+// Policy represents the thresholds at which failed attempts for a key are throttled. Once the
+// number of failures reaches BackoffAfter, each additional failure locks the key for an
+// exponentially increasing delay. Once the number of failures reaches LockoutAfter, the key is
+// locked for the full lockout duration. A threshold of zero disables that stage.
+type Policy struct {
+	BackoffAfter int
+	LockoutAfter int
+}
+
+// Store provides methods for tracking failed attempts and locking keys that fail too often.
+type Store interface {
+	Wait(key string) (time.Duration, error)
+	Fail(key string, policy Policy) (time.Duration, error)
+	Reset(key string) error
+	Close() error
+}
+
+// StoreConfig represents configuration options for a redis throttle store.
+type StoreConfig struct {
+	Redis     string
+	Window    time.Duration
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Lockout   time.Duration
+}
+
+type store struct {
+	redis     *redis.Pool
+	window    time.Duration
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	lockout   time.Duration
+}
+
+// NewStore creates a new redis throttle store.
+func NewStore(cfg StoreConfig) (Store, error) {
+	r := &redis.Pool{
+		IdleTimeout: 3 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(cfg.Redis)
+		},
+	}
+
+	conn := r.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("PING"); err != nil {
+		return nil, err
+	}
+
+	s := &store{
+		redis:     r,
+		window:    cfg.Window,
+		baseDelay: cfg.BaseDelay,
+		maxDelay:  cfg.MaxDelay,
+		lockout:   cfg.Lockout,
+	}
+	return s, nil
+}
+
+// Wait returns the remaining time that the key is locked for. A zero duration is returned if the
+// key is not locked.
+func (s *store) Wait(key string) (time.Duration, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	lockedUntil, err := redis.Int64(conn.Do("HGET", failuresPrefix+key, "locked_until"))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	wait := time.Until(time.Unix(lockedUntil, 0))
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+// Fail records a failed attempt for the key and returns the time that the key is now locked for
+// according to the provided policy.
+func (s *store) Fail(key string, policy Policy) (time.Duration, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	delay, err := redis.Int64(cmdRecordFailure.Do(conn, failuresPrefix+key,
+		time.Now().Unix(),
+		int(s.window.Seconds()),
+		policy.BackoffAfter,
+		policy.LockoutAfter,
+		int(s.baseDelay.Seconds()),
+		int(s.maxDelay.Seconds()),
+		int(s.lockout.Seconds()),
+	))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(delay) * time.Second, nil
+}
+
+// Reset clears all failed attempts for the key and unlocks it.
+func (s *store) Reset(key string) error {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", failuresPrefix+key)
+	return err
+}
+
+// Close closes the underlying redis connection.
+func (s *store) Close() error {
+	return s.redis.Close()
+}
@@ -0,0 +1,40 @@
+package twofactor
+
+import (
+	"database/sql"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Settings represents the two-factor authentication settings of an account. Two-factor
+// authentication is enabled once the enrollment has been confirmed.
+type Settings struct {
+	Secret       string       `db:"secret"`
+	LastUsedStep int64        `db:"last_used_step"`
+	ConfirmedAt  sql.NullTime `db:"confirmed_at"`
+}
+
+// Enrollment represents a pending two-factor authentication enrollment. The secret and provisioning
+// uri are used to add the account to an authenticator app.
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes represents the one-time recovery codes that are issued when two-factor
+// authentication is enabled.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// Code represents a code from an authenticator app or a recovery code supplied by the user.
+type Code struct {
+	Code string `json:"code"`
+}
+
+// Validate validates code data.
+func (c Code) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Code, validation.Required),
+	)
+}
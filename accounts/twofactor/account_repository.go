@@ -0,0 +1,200 @@
+package twofactor
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrAccountNotFound is used when an account could not be found in the account repository.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrGuestAccount is used when attempting to enroll a guest account in two-factor authentication.
+// Guest accounts have no email address to identify them in authenticator apps and no password to
+// protect with a second factor.
+var ErrGuestAccount = errors.New("guest account")
+
+// ErrNotEnrolled is used when an account has not started two-factor authentication enrollment.
+var ErrNotEnrolled = errors.New("two-factor authentication not enrolled")
+
+// ErrAlreadyEnabled is used when attempting to enroll an account that already has two-factor
+// authentication enabled.
+var ErrAlreadyEnabled = errors.New("two-factor authentication already enabled")
+
+// ErrInvalidCode is used when a supplied code is incorrect or has already been used.
+var ErrInvalidCode = errors.New("invalid two-factor authentication code")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	GetEmail(id int) (string, error)
+	Get(id int) (Settings, error)
+	SetSecret(id int, secret string) error
+	Confirm(id int, step int64, codeHashes []string) error
+	UseStep(id int, step int64) error
+	UseRecoveryCode(id int, codeHash string) error
+	Delete(id int) error
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// GetEmail retrieves the email of the account with the given id.
+func (r *accountRepository) GetEmail(id int) (string, error) {
+	const q = `SELECT email FROM accounts WHERE id = $1`
+
+	var email sql.NullString
+	if err := r.db.Get(&email, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrAccountNotFound
+		}
+		return "", err
+	}
+	if !email.Valid {
+		return "", ErrGuestAccount
+	}
+	return email.String, nil
+}
+
+// Get retrieves the two-factor authentication settings of the account with the given id.
+func (r *accountRepository) Get(id int) (Settings, error) {
+	const q = `SELECT secret, last_used_step, confirmed_at FROM two_factor WHERE account_id = $1`
+
+	var settings Settings
+	if err := r.db.Get(&settings, q, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return settings, ErrNotEnrolled
+		}
+		return settings, err
+	}
+	return settings, nil
+}
+
+// SetSecret stores a new unconfirmed secret for the account with the given id, replacing any
+// previous unconfirmed secret.
+func (r *accountRepository) SetSecret(id int, secret string) error {
+	const q = `INSERT INTO two_factor (account_id, secret) VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+		WHERE two_factor.confirmed_at IS NULL`
+
+	res, err := r.db.Exec(q, id, secret)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAlreadyEnabled
+	}
+	return nil
+}
+
+// Confirm enables two-factor authentication for the account with the given id and replaces its
+// recovery codes.
+func (r *accountRepository) Confirm(id int, step int64, codeHashes []string) error {
+	const (
+		qConfirm     = `UPDATE two_factor SET confirmed_at = now(), last_used_step = $2 WHERE account_id = $1 AND confirmed_at IS NULL`
+		qDeleteCodes = `DELETE FROM recovery_codes WHERE account_id = $1`
+		qInsertCode  = `INSERT INTO recovery_codes (account_id, code_hash) VALUES ($1, $2)`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(qConfirm, id, step)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAlreadyEnabled
+	}
+
+	if _, err := tx.Exec(qDeleteCodes, id); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(qInsertCode, id, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseStep records the time step of a successfully validated code. Codes from the same or earlier
+// time steps are rejected so that an observed code cannot be replayed.
+func (r *accountRepository) UseStep(id int, step int64) error {
+	const q = `UPDATE two_factor SET last_used_step = $2 WHERE account_id = $1 AND last_used_step < $2`
+
+	res, err := r.db.Exec(q, id, step)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used.
+func (r *accountRepository) UseRecoveryCode(id int, codeHash string) error {
+	const q = `UPDATE recovery_codes SET used_at = now() WHERE account_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	res, err := r.db.Exec(q, id, codeHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// Delete removes the two-factor authentication settings and recovery codes of the account with the
+// given id.
+func (r *accountRepository) Delete(id int) error {
+	const (
+		qDeleteSettings = `DELETE FROM two_factor WHERE account_id = $1`
+		qDeleteCodes    = `DELETE FROM recovery_codes WHERE account_id = $1`
+	)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(qDeleteSettings, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(qDeleteCodes, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
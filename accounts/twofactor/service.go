@@ -0,0 +1,173 @@
+package twofactor
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"untitled_game/accounts/audit"
+	"untitled_game/accounts/session"
+	"untitled_game/core/token"
+	"untitled_game/core/totp"
+)
+
+// ErrNotEnabled is used when a code is supplied for an account that does not have two-factor
+// authentication enabled.
+var ErrNotEnabled = errors.New("two-factor authentication not enabled")
+
+// recoveryCodeCount is the number of recovery codes that are issued when two-factor authentication
+// is enabled.
+const recoveryCodeCount = 10
+
+// recoveryCodeLength is the number of characters in a recovery code, excluding the separator.
+const recoveryCodeLength = 10
+
+// Service provides two-factor authentication related services.
+type Service interface {
+	Enroll(id int) (Enrollment, error)
+	Confirm(id int, code string, meta session.Metadata) (RecoveryCodes, error)
+	Disable(id int, code string, meta session.Metadata) error
+	Enabled(id int) (bool, error)
+	Verify(id int, code string) error
+}
+
+// auditor records security events in the audit log.
+type auditor interface {
+	Record(e audit.Entry)
+}
+
+type service struct {
+	accounts AccountRepository
+	audit    auditor
+	issuer   string
+}
+
+// NewService creates a new two-factor authentication service. The issuer is displayed alongside the
+// account email in authenticator apps.
+func NewService(accounts AccountRepository, audit auditor, issuer string) Service {
+	return &service{accounts, audit, issuer}
+}
+
+// Enroll starts two-factor authentication enrollment for the account with the given id by
+// generating a new secret. Two-factor authentication is not enabled until the enrollment is
+// confirmed with a valid code.
+func (s *service) Enroll(id int) (Enrollment, error) {
+	email, err := s.accounts.GetEmail(id)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if err := s.accounts.SetSecret(id, secret); err != nil {
+		return Enrollment{}, err
+	}
+	return Enrollment{Secret: secret, URI: totp.URI(s.issuer, email, secret)}, nil
+}
+
+// Confirm enables two-factor authentication for the account with the given id if the code is valid
+// for the pending enrollment. A new set of recovery codes is returned. The recovery codes are only
+// stored hashed, so this is the only time they are available.
+func (s *service) Confirm(id int, code string, meta session.Metadata) (RecoveryCodes, error) {
+	settings, err := s.accounts.Get(id)
+	if err != nil {
+		return RecoveryCodes{}, err
+	}
+	if settings.ConfirmedAt.Valid {
+		return RecoveryCodes{}, ErrAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(settings.Secret, code, time.Now())
+	if !ok {
+		return RecoveryCodes{}, ErrInvalidCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		t, err := token.Generate(recoveryCodeLength)
+		if err != nil {
+			return RecoveryCodes{}, err
+		}
+		t = strings.ToLower(t)
+
+		codes[i] = t[:recoveryCodeLength/2] + "-" + t[recoveryCodeLength/2:]
+		hashes[i] = token.Hash(t)
+	}
+
+	if err := s.accounts.Confirm(id, step, hashes); err != nil {
+		return RecoveryCodes{}, err
+	}
+
+	s.record(audit.EventTwoFactorEnabled, id, meta, nil)
+	return RecoveryCodes{codes}, nil
+}
+
+// Disable turns off two-factor authentication for the account with the given id after verifying
+// the supplied code.
+func (s *service) Disable(id int, code string, meta session.Metadata) error {
+	if err := s.Verify(id, code); err != nil {
+		return err
+	}
+
+	if err := s.accounts.Delete(id); err != nil {
+		return err
+	}
+
+	s.record(audit.EventTwoFactorDisabled, id, meta, nil)
+	return nil
+}
+
+// Enabled reports whether two-factor authentication is enabled for the account with the given id.
+func (s *service) Enabled(id int) (bool, error) {
+	settings, err := s.accounts.Get(id)
+	if err != nil {
+		if errors.Is(err, ErrNotEnrolled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return settings.ConfirmedAt.Valid, nil
+}
+
+// Verify checks a code for the account with the given id. The code may either be a code from the
+// authenticator app or one of the account's unused recovery codes. Recovery codes are consumed
+// when used.
+func (s *service) Verify(id int, code string) error {
+	settings, err := s.accounts.Get(id)
+	if err != nil {
+		if errors.Is(err, ErrNotEnrolled) {
+			return ErrNotEnabled
+		}
+		return err
+	}
+	if !settings.ConfirmedAt.Valid {
+		return ErrNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(settings.Secret, code, time.Now()); ok {
+		return s.accounts.UseStep(id, step)
+	}
+	return s.accounts.UseRecoveryCode(id, token.Hash(normalizeRecoveryCode(code)))
+}
+
+// normalizeRecoveryCode strips separators and whitespace from a recovery code and lowercases it so
+// that codes can be entered in any format.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// record records an audit event that was caused by and affects the account with the given id.
+func (s *service) record(eventType string, id int, meta session.Metadata, payload interface{}) {
+	s.audit.Record(audit.Entry{
+		Type:      eventType,
+		ActorID:   id,
+		AccountID: id,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Payload:   payload,
+	})
+}
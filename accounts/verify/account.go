@@ -0,0 +1,16 @@
+package verify
+
+import validation "github.com/go-ozzo/ozzo-validation/v4"
+
+// Token represents an email verification token that is supplied by the user to verify ownership of
+// the email address associated with their account.
+type Token struct {
+	Token string `json:"token"`
+}
+
+// Validate validates verification token data.
+func (t Token) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Token, validation.Required),
+	)
+}
@@ -0,0 +1,79 @@
+package verify
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrInvalidToken is used when a verification token does not match any unverified account or the
+// token has expired.
+var ErrInvalidToken = errors.New("invalid verification token")
+
+// ErrAlreadyVerified is used when attempting to issue a verification token for an account that has
+// already been verified.
+var ErrAlreadyVerified = errors.New("account already verified")
+
+// ErrGuestAccount is used when attempting to issue a verification token for a guest account, which
+// does not have an email address until it is upgraded.
+var ErrGuestAccount = errors.New("guest account")
+
+// AccountRepository provides methods for interacting with an account store.
+type AccountRepository interface {
+	Verify(tokenHash string) error
+	SetToken(id int, tokenHash string) (string, error)
+}
+
+type accountRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountRepository creates a new postgres account repository.
+func NewAccountRepository(db *sqlx.DB) AccountRepository {
+	return &accountRepository{db}
+}
+
+// Verify marks the account that owns the verification token as verified and clears the token so
+// that it cannot be used again.
+func (r *accountRepository) Verify(tokenHash string) error {
+	const q = `UPDATE accounts SET verified_at = now(), verification_token = NULL, verification_token_expires_at = NULL WHERE verification_token = $1 AND verification_token_expires_at > now() AND verified_at IS NULL`
+
+	res, err := r.db.Exec(q, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// SetToken replaces the verification token of an unverified account and returns the email address
+// of the account. Guest accounts are left unchanged.
+func (r *accountRepository) SetToken(id int, tokenHash string) (string, error) {
+	const q = `UPDATE accounts SET verification_token = $2, verification_token_expires_at = now() + interval '1 day' WHERE id = $1 AND verified_at IS NULL AND email IS NOT NULL RETURNING email`
+	const guestQ = `SELECT email IS NULL FROM accounts WHERE id = $1`
+
+	var email string
+	if err := r.db.Get(&email, q, id, tokenHash); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+
+		var guest bool
+		if err := r.db.Get(&guest, guestQ, id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+		if guest {
+			return "", ErrGuestAccount
+		}
+		return "", ErrAlreadyVerified
+	}
+	return email, nil
+}
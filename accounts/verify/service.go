@@ -0,0 +1,69 @@
+package verify
+
+import (
+	"fmt"
+	"net/url"
+	"untitled_game/core/mail"
+	"untitled_game/core/token"
+)
+
+// verificationEmail is the body of the email that is sent to confirm an account email address.
+const verificationEmail = `Welcome to Untitled Game!
+
+Please confirm your email address by visiting the link below:
+
+%s
+
+This link expires in 24 hours. If you did not create an account, you can safely ignore this email.
+`
+
+// Service provides email verification related services.
+type Service interface {
+	Verify(token string) error
+	Resend(id int) error
+	Send(email string, token string) error
+}
+
+type service struct {
+	accounts AccountRepository
+	mailer   mail.Mailer
+	linkURL  string
+}
+
+// NewService creates a new email verification service. Verification links sent to users point to
+// the provided link url with the verification token appended as a query parameter. The mailer should
+// deliver messages in the background, since verification links are sent after the account has been
+// created, and a failure to send one must not fail the request that created it. Users can request a
+// new link if the email does not arrive.
+func NewService(accounts AccountRepository, mailer mail.Mailer, linkURL string) Service {
+	return &service{accounts, mailer, linkURL}
+}
+
+// Verify consumes a verification token and marks the owning account as verified.
+func (s *service) Verify(t string) error {
+	return s.accounts.Verify(token.Hash(t))
+}
+
+// Resend rotates the verification token of the account with the given id and emails the new token
+// to the account email address. Any previously issued token is invalidated.
+func (s *service) Resend(id int) error {
+	t, err := token.Generate(32)
+	if err != nil {
+		return err
+	}
+
+	email, err := s.accounts.SetToken(id, token.Hash(t))
+	if err != nil {
+		return err
+	}
+	return s.Send(email, t)
+}
+
+// Send emails a verification link containing the token to the provided email address.
+func (s *service) Send(email string, t string) error {
+	return s.mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body:    fmt.Sprintf(verificationEmail, s.linkURL+"?token="+url.QueryEscape(t)),
+	})
+}
@@ -2,25 +2,56 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strconv"
 	"syscall"
 	"time"
+	"untitled_game/accounts/admin"
+	"untitled_game/accounts/audit"
 	"untitled_game/accounts/auth"
+	"untitled_game/accounts/ban"
 	"untitled_game/accounts/config"
+	"untitled_game/accounts/deletion"
+	"untitled_game/accounts/displayname"
+	"untitled_game/accounts/emailchange"
+	"untitled_game/accounts/export"
+	"untitled_game/accounts/guest"
 	"untitled_game/accounts/handler"
+	"untitled_game/accounts/introspect"
+	"untitled_game/accounts/invite"
+	"untitled_game/accounts/loginhistory"
+	"untitled_game/accounts/password"
+	"untitled_game/accounts/recovery"
 	"untitled_game/accounts/register"
+	"untitled_game/accounts/role"
 	"untitled_game/accounts/session"
+	"untitled_game/accounts/throttle"
+	"untitled_game/accounts/twofactor"
+	"untitled_game/accounts/verify"
+	"untitled_game/core/captcha"
+	"untitled_game/core/hasher"
+	"untitled_game/core/jwt"
+	"untitled_game/core/mail"
 	"untitled_game/core/migrate"
 	"untitled_game/core/postgres"
+	"untitled_game/core/ratelimit"
+
+	"github.com/jmoiron/sqlx"
 )
 
 func main() {
 	var flagConfig = flag.String("config", "", "path to config file")
+	var flagConfigOverride = flag.String("config-override", "", "path to a config file that overrides options of the config file")
+	var flagNormalizeEmails = flag.Bool("normalize-emails", false, "renormalize the canonical emails of existing accounts and exit")
 	flag.Parse()
 
 	log := log.New(os.Stdout, "accounts: ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
@@ -29,11 +60,25 @@ func main() {
 		log.Fatalf("no configuration file was provided. use --config path/to/config.yml to specify a configuration file.")
 	}
 
-	cfg, err := config.Load(*flagConfig)
+	paths := []string{*flagConfig}
+	if *flagConfigOverride != "" {
+		paths = append(paths, *flagConfigOverride)
+	}
+
+	cfg, err := config.Load(paths...)
 	if err != nil {
 		log.Fatalf("could not load config: %v", err)
 	}
 
+	if cfg.Introspection.Enabled && len(cfg.Introspection.ServiceKeys) == 0 {
+		log.Fatalf("token introspection is enabled but no service keys are configured")
+	}
+
+	trustedProxies, err := parseNetworks(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("could not parse trusted proxies: %v", err)
+	}
+
 	if err := migrate.Migrate(cfg.Database.Address); err != nil {
 		log.Fatalf("could not perform database migration: %v", err)
 	}
@@ -51,24 +96,279 @@ func main() {
 		log.Fatalf("database status check failed: %v", err)
 	}
 
+	if *flagNormalizeEmails {
+		n, err := register.NewAccountRepository(db).Normalize()
+		if err != nil {
+			log.Fatalf("could not normalize account emails: %v", err)
+		}
+		log.Printf("normalized %d account emails", n)
+
+		if err := db.Close(); err != nil {
+			log.Printf("could not close database connection: %v", err)
+		}
+		return
+	}
+
 	sess, err := session.NewStore(session.StoreConfig{
-		Redis:      cfg.Sessions.Redis,
-		SessionTTL: cfg.Sessions.SessionExpiryMins * time.Minute,
-		UserTTL:    cfg.Sessions.UserExpiryMins * time.Minute,
+		Redis:        cfg.Sessions.Redis,
+		AccessTTL:    cfg.Sessions.AccessExpiryMins * time.Minute,
+		SessionTTL:   cfg.Sessions.SessionExpiryMins * time.Minute,
+		UserTTL:      cfg.Sessions.UserExpiryMins * time.Minute,
+		ChallengeTTL: cfg.Sessions.ChallengeExpiryMins * time.Minute,
+	})
+	if err != nil {
+		log.Fatalf("could not create session store: %v", err)
+	}
+
+	throttleStore, err := throttle.NewStore(throttle.StoreConfig{
+		Redis:     cfg.Sessions.Redis,
+		Window:    cfg.LoginThrottle.WindowMins * time.Minute,
+		BaseDelay: cfg.LoginThrottle.BaseDelaySecs * time.Second,
+		MaxDelay:  cfg.LoginThrottle.MaxDelaySecs * time.Second,
+		Lockout:   cfg.LoginThrottle.LockoutMins * time.Minute,
 	})
+	if err != nil {
+		log.Fatalf("could not create throttle store: %v", err)
+	}
 
-	authService := auth.NewService(sess, auth.NewAccountRepository(db))
-	registerService := register.NewService(register.NewAccountRepository(db))
+	limiter, err := newLimiter(cfg.RateLimits)
+	if err != nil {
+		log.Fatalf("could not create rate limiter: %v", err)
+	}
+
+	mailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		log.Fatalf("could not create mailer: %v", err)
+	}
+	asyncMailer := mail.NewAsyncMailer(mailer, log)
+
+	captchaVerifier, err := newCaptchaVerifier(cfg.BotProtection)
+	if err != nil {
+		log.Fatalf("could not create captcha verifier: %v", err)
+	}
+
+	botEndpoints := make(map[string]captcha.Endpoint, len(cfg.BotProtection.Endpoints))
+	for name, e := range cfg.BotProtection.Endpoints {
+		botEndpoints[name] = captcha.Endpoint{Enabled: e.Enabled, After: e.After, Window: e.WindowSecs * time.Second}
+	}
+	botGuard := captcha.NewGuard(captchaVerifier, limiter, botEndpoints)
+
+	passwordHasher, err := hasher.New(hasher.Config{
+		Algorithm:  cfg.PasswordHashing.Algorithm,
+		BcryptCost: cfg.PasswordHashing.BcryptCost,
+		Argon2: hasher.Argon2Params{
+			Memory:      cfg.PasswordHashing.Argon2MemoryKiB,
+			Iterations:  cfg.PasswordHashing.Argon2Iterations,
+			Parallelism: cfg.PasswordHashing.Argon2Parallelism,
+		},
+	})
+	if err != nil {
+		log.Fatalf("could not create password hasher: %v", err)
+	}
+
+	signer, err := newSigner(log, cfg.GameTokens)
+	if err != nil {
+		log.Fatalf("could not create game token signer: %v", err)
+	}
+
+	roleService := role.NewService(sess, role.NewAccountRepository(db))
+	for _, email := range cfg.Admin.BootstrapEmails {
+		if err := roleService.Bootstrap(email); err != nil {
+			if errors.Is(err, role.ErrAccountNotFound) {
+				log.Printf("skipping admin bootstrap for %s: no verified account", email)
+				continue
+			}
+			log.Fatalf("could not bootstrap admin accounts: %v", err)
+		}
+	}
+
+	auditService := audit.NewService(audit.NewAccountRepository(db), log)
+	loginHistoryService := loginhistory.NewService(sess, loginhistory.NewAccountRepository(db), asyncMailer, log, loginhistory.ServiceConfig{
+		LinkURL:   cfg.Mail.LinkBaseURL + "/logins/revoke",
+		RevokeTTL: cfg.LoginHistory.RevokeHours * time.Hour,
+		Retention: cfg.LoginHistory.RetentionDays * 24 * time.Hour,
+	})
+	banService := ban.NewService(sess, ban.NewAccountRepository(db))
+	twoFactorService := twofactor.NewService(twofactor.NewAccountRepository(db), auditService, cfg.TwoFactor.Issuer)
+	authService := auth.NewService(sess, auth.NewAccountRepository(db), passwordHasher, twoFactorService, throttleStore, signer, banService, roleService, auditService, loginHistoryService, botGuard, log, auth.ServiceConfig{
+		EmailPolicy: throttle.Policy{
+			BackoffAfter: cfg.LoginThrottle.EmailBackoffAfter,
+			LockoutAfter: cfg.LoginThrottle.EmailLockoutAfter,
+		},
+		IPPolicy: throttle.Policy{
+			BackoffAfter: cfg.LoginThrottle.IPBackoffAfter,
+			LockoutAfter: cfg.LoginThrottle.IPLockoutAfter,
+		},
+		SecondFactorPolicy: throttle.Policy{
+			BackoffAfter: cfg.LoginThrottle.SecondFactorBackoffAfter,
+			LockoutAfter: cfg.LoginThrottle.SecondFactorLockoutAfter,
+		},
+		ProtectEnumeration: cfg.Enumeration.Protect,
+	})
+	verifyService := verify.NewService(verify.NewAccountRepository(db), asyncMailer, cfg.Mail.LinkBaseURL+"/verify")
+	registerService := register.NewService(register.NewAccountRepository(db), passwordHasher, verifyService, asyncMailer, auditService, botGuard, register.ServiceConfig{
+		ProtectEnumeration: cfg.Enumeration.Protect,
+		ResetURL:           cfg.Mail.LinkBaseURL + "/password/forgot",
+		RequireInvite:      cfg.Registration.RequireInvite,
+	})
+	passwordService := password.NewService(sess, password.NewAccountRepository(db), passwordHasher, auditService)
+	recoveryService := recovery.NewService(sess, recovery.NewAccountRepository(db), passwordHasher, asyncMailer, auditService, recovery.ServiceConfig{
+		LinkURL:  cfg.Mail.LinkBaseURL + "/password/reset",
+		TokenTTL: cfg.PasswordReset.TokenExpiryMins * time.Minute,
+	})
+
+	emailChangeService := emailchange.NewService(sess, emailchange.NewAccountRepository(db), passwordHasher, asyncMailer, auditService, emailchange.ServiceConfig{
+		ConfirmURL: cfg.Mail.LinkBaseURL + "/account/email/confirm",
+		RevertURL:  cfg.Mail.LinkBaseURL + "/account/email/revert",
+		ConfirmTTL: cfg.EmailChange.ConfirmHours * time.Hour,
+		RevertTTL:  cfg.EmailChange.RevertHours * time.Hour,
+	})
+
+	guestService := guest.NewService(sess, guest.NewAccountRepository(db), passwordHasher, verifyService, banService, roleService, auditService, guest.ServiceConfig{
+		Expiry:        cfg.Guests.ExpiryDays * 24 * time.Hour,
+		RequireInvite: cfg.Registration.RequireInvite,
+	})
+
+	displayNameService, err := newDisplayNameService(db, cfg.DisplayNames)
+	if err != nil {
+		log.Fatalf("could not create display name service: %v", err)
+	}
+
+	if cfg.AccountDeletion.PurgeMode != "delete" && cfg.AccountDeletion.PurgeMode != "anonymize" {
+		log.Fatalf("unknown account purge mode: %q", cfg.AccountDeletion.PurgeMode)
+	}
+
+	deletionService := deletion.NewService(sess, deletion.NewAccountRepository(db), passwordHasher, asyncMailer, auditService, deletion.ServiceConfig{
+		GracePeriod: cfg.AccountDeletion.GraceDays * 24 * time.Hour,
+		Anonymize:   cfg.AccountDeletion.PurgeMode == "anonymize",
+	})
+
+	inviteService := invite.NewService(invite.NewAccountRepository(db), auditService)
+
+	exportService := export.NewService(export.NewAccountRepository(db), export.ServiceConfig{
+		Sources: []export.Source{
+			export.NewSource("sessions", func(id int) (interface{}, error) {
+				return sess.List(id)
+			}),
+			export.NewSource("display_names", func(id int) (interface{}, error) {
+				return displayNameService.History(id)
+			}),
+			export.NewSource("bans", func(id int) (interface{}, error) {
+				return banService.List(id)
+			}),
+			export.NewSource("roles", func(id int) (interface{}, error) {
+				return roleService.Get(id)
+			}),
+			export.NewSource("login_history", func(id int) (interface{}, error) {
+				return loginHistoryService.List(id)
+			}),
+			export.NewSource("two_factor", func(id int) (interface{}, error) {
+				enabled, err := twoFactorService.Enabled(id)
+				return map[string]bool{"enabled": enabled}, err
+			}),
+			export.NewSource("email_changes", func(id int) (interface{}, error) {
+				return emailChangeService.History(id)
+			}),
+			export.NewSource("audit_events", func(id int) (interface{}, error) {
+				return auditService.History(id)
+			}),
+			export.NewSource("invite_redemptions", func(id int) (interface{}, error) {
+				return inviteService.RedeemedBy(id)
+			}),
+		},
+		Retention:    cfg.DataExports.RetentionHours * time.Hour,
+		ClaimTimeout: cfg.DataExports.ClaimTimeoutMins * time.Minute,
+	})
+
+	services := handler.Services{
+		Auth:         authService,
+		Register:     registerService,
+		Verify:       verifyService,
+		Recovery:     recoveryService,
+		Password:     passwordService,
+		TwoFactor:    twoFactorService,
+		Introspect:   introspect.NewService(sess, banService),
+		Guest:        guestService,
+		DisplayName:  displayNameService,
+		Deletion:     deletionService,
+		Export:       exportService,
+		Ban:          banService,
+		Audit:        auditService,
+		LoginHistory: loginHistoryService,
+		EmailChange:  emailChangeService,
+		Invite:       inviteService,
+		Admin:        admin.NewService(sess, admin.NewAccountRepository(db), recoveryService, authService, banService, roleService, auditService),
+	}
+
+	rateLimits := make(map[string]ratelimit.Limit, len(cfg.RateLimits.Routes))
+	for name, l := range cfg.RateLimits.Routes {
+		rateLimits[name] = ratelimit.Limit{Requests: l.Requests, Window: l.WindowSecs * time.Second}
+	}
+
+	handlerConfig := handler.Config{
+		Signer:             signer,
+		TrustedProxies:     trustedProxies,
+		Introspection:      cfg.Introspection.Enabled,
+		ServiceKeys:        cfg.Introspection.ServiceKeys,
+		Limiter:            limiter,
+		RateLimits:         rateLimits,
+		ProtectEnumeration: cfg.Enumeration.Protect,
+	}
 
 	srv := http.Server{
 		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
-		Handler:           handler.New(log, sess, authService, registerService),
+		Handler:           handler.New(log, sess, services, handlerConfig),
 		ReadTimeout:       cfg.Server.ReadTimeoutSecs * time.Second,
 		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeoutSecs * time.Second,
 		WriteTimeout:      cfg.Server.WriteTimeoutSecs * time.Second,
 		IdleTimeout:       cfg.Server.IdleTimeoutSecs * time.Second,
 	}
 
+	stop := make(chan struct{})
+
+	go schedule(cfg.Guests.PurgeIntervalMins*time.Minute, stop, func() {
+		n, err := guestService.Purge()
+		if err != nil {
+			log.Printf("could not purge expired guest accounts: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("purged %d expired guest accounts", n)
+		}
+	})
+
+	go schedule(cfg.AccountDeletion.PurgeIntervalMins*time.Minute, stop, func() {
+		n, err := deletionService.Purge()
+		if err != nil {
+			log.Printf("could not purge deleted accounts: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("purged %d deleted accounts", n)
+		}
+	})
+
+	go schedule(cfg.DataExports.ProcessIntervalSecs*time.Second, stop, func() {
+		n, err := exportService.Process()
+		if err != nil {
+			log.Printf("could not process data exports: %v", err)
+		}
+		if n > 0 {
+			log.Printf("generated %d data exports", n)
+		}
+	})
+
+	go schedule(cfg.LoginHistory.PurgeIntervalMins*time.Minute, stop, func() {
+		n, err := loginHistoryService.Purge()
+		if err != nil {
+			log.Printf("could not purge login history: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("purged %d logins from login history", n)
+		}
+	})
+
 	go func() {
 		log.Printf("starting server on port: %d", cfg.Server.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -84,6 +384,8 @@ func main() {
 	log.Printf("shutdown signal received: %v", sig)
 	log.Printf("starting graceful server shutdown")
 
+	close(stop)
+
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownGraceSecs*time.Second)
 	defer cancel()
 
@@ -92,6 +394,10 @@ func main() {
 		log.Printf("could not shutdown server gracefully: %v", err)
 	}
 
+	if err := asyncMailer.Close(); err != nil {
+		log.Printf("could not send pending emails: %v", err)
+	}
+
 	if err := db.Close(); err != nil {
 		log.Printf("could not close database connection: %v", err)
 	}
@@ -100,5 +406,173 @@ func main() {
 		log.Printf("could not close session store: %v", err)
 	}
 
+	if err := throttleStore.Close(); err != nil {
+		log.Printf("could not close throttle store: %v", err)
+	}
+
+	if err := limiter.Close(); err != nil {
+		log.Printf("could not close rate limiter: %v", err)
+	}
+
 	log.Println("server shutdown complete, exiting")
 }
+
+// newMailer creates the mailer selected by the mail configuration.
+func newMailer(cfg config.Mail) (mail.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}), nil
+	case "outbox":
+		return mail.NewOutboxMailer(cfg.OutboxDir, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %q", cfg.Driver)
+	}
+}
+
+// newLimiter creates the rate limiter selected by the rate limits configuration.
+func newLimiter(cfg config.RateLimits) (ratelimit.Limiter, error) {
+	switch cfg.Backend {
+	case "redis":
+		return ratelimit.NewRedisLimiter(ratelimit.RedisConfig{Redis: cfg.Redis})
+	case "memory":
+		return ratelimit.NewMemoryLimiter(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %q", cfg.Backend)
+	}
+}
+
+// newCaptchaVerifier creates the captcha verifier selected by the bot protection configuration.
+func newCaptchaVerifier(cfg config.BotProtection) (captcha.Verifier, error) {
+	client := &http.Client{Timeout: cfg.TimeoutSecs * time.Second}
+	switch cfg.Driver {
+	case "hcaptcha":
+		return newHTTPVerifier(cfg, captcha.HCaptchaURL, client), nil
+	case "turnstile":
+		return newHTTPVerifier(cfg, captcha.TurnstileURL, client), nil
+	case "stub":
+		return captcha.NewStubVerifier(cfg.StubResponse), nil
+	default:
+		return nil, fmt.Errorf("unknown captcha driver: %q", cfg.Driver)
+	}
+}
+
+// newHTTPVerifier creates a captcha verifier that uses the site verification api of a provider. The
+// default url of the provider is used unless a verify url is configured.
+func newHTTPVerifier(cfg config.BotProtection, defaultURL string, client *http.Client) captcha.Verifier {
+	url := cfg.VerifyURL
+	if url == "" {
+		url = defaultURL
+	}
+	return captcha.NewHTTPVerifier(captcha.HTTPConfig{URL: url, Secret: cfg.Secret, Client: client})
+}
+
+// parseNetworks parses a list of networks in CIDR notation.
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks[i] = network
+	}
+	return networks, nil
+}
+
+// newSigner creates the game token signer from the game tokens configuration. If no keys are
+// configured and ephemeral keys are allowed, an ephemeral key is generated, and tokens signed with it
+// become unverifiable once the server restarts.
+func newSigner(log *log.Logger, cfg config.GameTokens) (jwt.Signer, error) {
+	var keys []jwt.Key
+	for _, k := range cfg.Keys {
+		data, err := ioutil.ReadFile(k.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwt.ParseKeyPEM(k.ID, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	activeKey := cfg.ActiveKey
+	if len(keys) == 0 {
+		if !cfg.AllowEphemeral {
+			return nil, errors.New("no game token signing keys configured")
+		}
+
+		key, err := jwt.GenerateKey("ephemeral")
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("no game token signing keys configured, using an ephemeral key")
+		keys = append(keys, key)
+		activeKey = key.ID
+	}
+
+	return jwt.NewSigner(jwt.SignerConfig{
+		Keys:        keys,
+		ActiveKeyID: activeKey,
+		Issuer:      cfg.Issuer,
+		Audience:    cfg.Audience,
+		TTL:         cfg.ExpirySecs * time.Second,
+	})
+}
+
+// newDisplayNameService creates the display name service from the display names configuration. The
+// blocked words are read from the configured word list file.
+func newDisplayNameService(db *sqlx.DB, cfg config.DisplayNames) (displayname.Service, error) {
+	pattern, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocked []string
+	if cfg.BlockedWordsFile != "" {
+		f, err := os.Open(cfg.BlockedWordsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		blocked, err = displayname.ReadWordlist(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return displayname.NewService(displayname.NewAccountRepository(db), displayname.ServiceConfig{
+		MinLength: cfg.MinLength,
+		MaxLength: cfg.MaxLength,
+		Pattern:   pattern,
+		Cooldown:  cfg.CooldownDays * 24 * time.Hour,
+		Filter:    displayname.NewFilter(cfg.ReservedWords, blocked),
+	}), nil
+}
+
+// schedule runs a job every interval until the stop channel is closed. The job is never run if the
+// interval is not positive.
+func schedule(interval time.Duration, stop <-chan struct{}, job func()) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			job()
+		case <-stop:
+			return
+		}
+	}
+}
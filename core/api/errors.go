@@ -28,19 +28,29 @@ var ErrInvalidRequestBody = Error{Message: "Invalid request body.", Status: http
 // request or interact with a particular resource.
 var ErrUnauthorized = Error{Message: "Unauthorized.", Status: http.StatusUnauthorized}
 
+// ErrForbidden is used when a request is authenticated, but the authenticated account does not have
+// permission to make the request.
+var ErrForbidden = Error{Message: "Forbidden.", Status: http.StatusForbidden}
+
 // ErrInvalidAuthToken is sent as an http response when the supplied auth token is invalid.
 var ErrInvalidAuthToken = Error{Message: "Invalid auth token.", Status: http.StatusUnauthorized}
 
+// ErrTooManyRequests is used when a request is rejected because the client has exceeded the rate
+// limit for the route.
+var ErrTooManyRequests = Error{Message: "Too many requests.", Status: http.StatusTooManyRequests}
+
 // ErrValidationError is used when the request body is formatted correctly, but one or more of the
 // fields does not meet some requirement. An example is this is requiring a minimum length on a
 // particular field.
 var ErrValidationError = Error{Message: "There were some validation errors.", Status: http.StatusBadRequest}
 
-// Error represents a custom error to be used as the response to an http request.
+// Error represents a custom error to be used as the response to an http request. Any headers are
+// added to the response along with the error.
 type Error struct {
 	Message string      `json:"message"`
 	Status  int         `json:"-"`
 	Details interface{} `json:"details,omitempty"`
+	Headers http.Header `json:"-"`
 }
 
 // WithDetails adds additional details to the error.
@@ -49,6 +59,18 @@ func (e Error) WithDetails(details interface{}) Error {
 	return e
 }
 
+// WithHeader adds a response header to the error. The headers of the original error are not
+// modified.
+func (e Error) WithHeader(key string, value string) Error {
+	headers := e.Headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Set(key, value)
+	e.Headers = headers
+	return e
+}
+
 // Error implements the error interface and returns the error message.
 func (e Error) Error() string {
 	return e.Message
@@ -60,3 +60,24 @@ func (h *Handler) Handle(method string, path string, handler http.HandlerFunc, m
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.router.ServeHTTP(w, r)
 }
+
+// Group represents a group of handler routes that share a path prefix and middleware.
+type Group struct {
+	h      *Handler
+	prefix string
+	mw     []Middleware
+}
+
+// Group creates a new route group. Routes that are added to the group are prefixed with the provided
+// path prefix and wrapped in the provided middleware, which runs before any route specific middleware.
+func (h *Handler) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{h, prefix, mw}
+}
+
+// Handle adds an http request handler to the group for a specific route and request method.
+func (g *Group) Handle(method string, path string, handler http.HandlerFunc, mw ...Middleware) {
+	groupMw := make([]Middleware, 0, len(g.mw)+len(mw))
+	groupMw = append(groupMw, g.mw...)
+	groupMw = append(groupMw, mw...)
+	g.h.Handle(method, g.prefix+path, handler, groupMw...)
+}
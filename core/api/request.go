@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Param returns the value of the named route parameter for the request. An empty string is returned
+// if the route does not contain a parameter with the provided name.
+func Param(r *http.Request, name string) string {
+	return httprouter.ParamsFromContext(r.Context()).ByName(name)
+}
+
+type contextKey int
+
+const contextKeyClientIP contextKey = iota
+
+// TrustProxies creates middleware that resolves the ip address of the client that made the request.
+// Requests are expected to be proxied by nginx, which sets the X-Real-IP header to the address of
+// the connecting client. The header is only honored if the request comes from one of the trusted
+// proxy networks, since any other client could set it to an address of its choosing.
+func TrustProxies(proxies []*net.IPNet) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ip := net.ParseIP(remoteHost(r))
+			for _, proxy := range proxies {
+				if ip == nil || !proxy.Contains(ip) {
+					continue
+				}
+				if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+					r = r.WithContext(context.WithValue(r.Context(), contextKeyClientIP, realIP))
+				}
+				break
+			}
+			next(w, r)
+		}
+	}
+}
+
+// ClientIP returns the ip address of the client that made the request. This is the address that
+// was resolved by the TrustProxies middleware, or the remote address of the request if the request
+// did not come from a trusted proxy.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(contextKeyClientIP).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// remoteHost returns the host of the remote address of the request.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
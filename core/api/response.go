@@ -56,6 +56,12 @@ func (r *responder) RespondError(w http.ResponseWriter, err error) {
 		return
 	}
 
+	for key, values := range e.Headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(e.Status)
 	r.checkErr(w.Write(bytes))
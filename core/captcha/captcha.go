@@ -0,0 +1,73 @@
+package captcha
+
+import (
+	"errors"
+	"time"
+	"untitled_game/core/ratelimit"
+)
+
+// ErrRequired is used when a request must pass bot verification but no captcha response was
+// supplied.
+var ErrRequired = errors.New("captcha required")
+
+// ErrFailed is used when a captcha response is rejected by the verifier.
+var ErrFailed = errors.New("captcha verification failed")
+
+// Verifier provides a method Verify for checking a captcha response that was solved by a client.
+// ErrFailed is returned if the response is invalid, expired or has already been used.
+type Verifier interface {
+	Verify(response string, ip string) error
+}
+
+// Endpoint represents the bot verification policy of an endpoint. If After is zero, then every
+// request to the endpoint must pass verification. Otherwise, verification is only required once
+// more than After requests have been made from the same client ip address within the window.
+type Endpoint struct {
+	Enabled bool
+	After   int
+	Window  time.Duration
+}
+
+// Guard provides a method Check for deciding whether a request to an endpoint must pass bot
+// verification, and verifying the captcha response if it does.
+type Guard interface {
+	Check(endpoint string, response string, ip string) error
+}
+
+type guard struct {
+	verifier  Verifier
+	counter   ratelimit.Limiter
+	endpoints map[string]Endpoint
+}
+
+// NewGuard creates a new guard that verifies captcha responses with the provided verifier. Requests
+// are counted per endpoint and client ip address with the provided limiter. Endpoints that are not
+// listed or not enabled never require verification.
+func NewGuard(verifier Verifier, counter ratelimit.Limiter, endpoints map[string]Endpoint) Guard {
+	return &guard{verifier, counter, endpoints}
+}
+
+// Check verifies the captcha response if the endpoint requires verification for the client ip
+// address. If verification is required, then ErrRequired is returned when the response is empty and
+// ErrFailed is returned when the response is rejected.
+func (g *guard) Check(endpoint string, response string, ip string) error {
+	e, ok := g.endpoints[endpoint]
+	if !ok || !e.Enabled {
+		return nil
+	}
+
+	if e.After > 0 {
+		res, err := g.counter.Allow("captcha:"+endpoint+":"+ip, ratelimit.Limit{Requests: e.After, Window: e.Window})
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			return nil
+		}
+	}
+
+	if response == "" {
+		return ErrRequired
+	}
+	return g.verifier.Verify(response, ip)
+}
@@ -0,0 +1,80 @@
+package captcha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Site verification urls of the supported captcha providers.
+const (
+	HCaptchaURL  = "https://api.hcaptcha.com/siteverify"
+	TurnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// HTTPConfig represents configuration options for an http verifier. The url is the site
+// verification url of the captcha provider, and the secret is the secret key of the site.
+type HTTPConfig struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+type httpVerifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// siteVerifyResponse represents the response of a site verification request. hCaptcha and Turnstile
+// share the same request and response format.
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// NewHTTPVerifier creates a new verifier that checks captcha responses with the site verification
+// api of a captcha provider such as hCaptcha or Cloudflare Turnstile.
+func NewHTTPVerifier(cfg HTTPConfig) Verifier {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &httpVerifier{
+		url:    cfg.URL,
+		secret: cfg.Secret,
+		client: cfg.Client,
+	}
+}
+
+// Verify submits the captcha response to the site verification url along with the client ip
+// address.
+func (v *httpVerifier) Verify(response string, ip string) error {
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {response},
+	}
+	if ip != "" {
+		form.Set("remoteip", ip)
+	}
+
+	res, err := v.client.PostForm(v.url, form)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("verify captcha: unexpected status: %s", res.Status)
+	}
+
+	var body siteVerifyResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return err
+	}
+	if !body.Success {
+		return ErrFailed
+	}
+	return nil
+}
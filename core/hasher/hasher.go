@@ -0,0 +1,94 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ErrMismatch is used when a password does not match a hash.
+var ErrMismatch = errors.New("password does not match hash")
+
+// ErrUnknownFormat is used when a hash is not in a format that is supported by any algorithm.
+var ErrUnknownFormat = errors.New("unknown hash format")
+
+// PasswordRules are the validation rules for new passwords. They apply wherever a password is set,
+// so that every account is held to the same rules. Existing passwords are not checked against them
+// when logging in.
+var PasswordRules = []validation.Rule{validation.Required, validation.RuneLength(8, 128)}
+
+// Algorithm names that can be selected in the hasher configuration.
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+// Hasher provides methods for hashing passwords and comparing passwords to hashes. NeedsRehash
+// reports whether a hash was created with an algorithm or parameters other than the ones the
+// hasher currently uses to create new hashes.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Compare(hash string, password string) error
+	NeedsRehash(hash string) bool
+}
+
+// Config represents configuration options for a hasher. New hashes are created with the selected
+// algorithm, and hashes created with any supported algorithm can be compared.
+type Config struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+type hasher struct {
+	preferred  Hasher
+	algorithms map[string]Hasher
+}
+
+// New creates a new hasher with the provided configuration.
+func New(cfg Config) (Hasher, error) {
+	algorithms := map[string]Hasher{
+		Bcrypt:   newBcrypt(cfg.BcryptCost),
+		Argon2id: newArgon2(cfg.Argon2),
+	}
+
+	preferred, ok := algorithms[cfg.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm: %q", cfg.Algorithm)
+	}
+	return &hasher{preferred, algorithms}, nil
+}
+
+// Hash hashes a password with the preferred algorithm.
+func (h *hasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+// Compare compares a password to a hash that was created with any supported algorithm.
+func (h *hasher) Compare(hash string, password string) error {
+	algorithm, ok := h.algorithms[identify(hash)]
+	if !ok {
+		return ErrUnknownFormat
+	}
+	return algorithm.Compare(hash, password)
+}
+
+// NeedsRehash reports whether the hash was created with an algorithm other than the preferred
+// algorithm or with outdated parameters.
+func (h *hasher) NeedsRehash(hash string) bool {
+	return h.algorithms[identify(hash)] != h.preferred || h.preferred.NeedsRehash(hash)
+}
+
+// identify returns the name of the algorithm that created the hash.
+func identify(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return Argon2id
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return Bcrypt
+	default:
+		return ""
+	}
+}
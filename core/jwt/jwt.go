@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidToken is used when a token is malformed or its signature is invalid.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrExpiredToken is used when a token has expired.
+var ErrExpiredToken = errors.New("token expired")
+
+// ErrUnknownKey is used when a token was signed with a key that is not in the key set.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// encoding is used to encode the segments of a token.
+var encoding = base64.RawURLEncoding
+
+// header represents the JOSE header of a token.
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Claims represents the claims carried by an access token. The subject is the account id formatted
+// as a string, as required by the JWT specification. The account id is also included as a number
+// for convenience.
+type Claims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ID        string `json:"jti,omitempty"`
+	AccountID int    `json:"account_id"`
+}
+
+// encodeSegment marshals a value into a base64url encoded token segment.
+func encodeSegment(v interface{}) (string, error) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(bytes), nil
+}
+
+// decodeSegment unmarshals a base64url encoded token segment into a value.
+func decodeSegment(segment string, v interface{}) error {
+	bytes, err := encoding.DecodeString(segment)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// split splits a token into its header, payload, and signature segments.
+func split(token string) ([]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	return parts, nil
+}
@@ -0,0 +1,186 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Supported signing algorithms.
+const (
+	EdDSA = "EdDSA"
+	ES256 = "ES256"
+)
+
+// errUnsupportedKey is used when a JSON web key has a key type or curve that cannot be used to
+// verify tokens.
+var errUnsupportedKey = errors.New("unsupported key type")
+
+// es256Size is the size in bytes of each of the r and s values of an ES256 signature.
+const es256Size = 32
+
+// Key represents a private signing key along with the key id that identifies it in the kid header
+// of signed tokens.
+type Key struct {
+	ID        string
+	Algorithm string
+	private   crypto.Signer
+}
+
+// NewKey creates a signing key from an Ed25519 or P-256 ECDSA private key.
+func NewKey(id string, private crypto.Signer) (Key, error) {
+	switch k := private.(type) {
+	case ed25519.PrivateKey:
+		return Key{id, EdDSA, k}, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return Key{}, errors.New("unsupported ecdsa curve: only P-256 is supported")
+		}
+		return Key{id, ES256, k}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported private key type: %T", private)
+	}
+}
+
+// GenerateKey creates a new random Ed25519 signing key.
+func GenerateKey(id string) (Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+	return NewKey(id, private)
+}
+
+// ParseKeyPEM creates a signing key from a PEM encoded PKCS #8 private key.
+func ParseKeyPEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no pem data found")
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return Key{}, err
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return Key{}, fmt.Errorf("unsupported private key type: %T", private)
+	}
+	return NewKey(id, signer)
+}
+
+// JWK returns the public part of the key as a JSON web key.
+func (k Key) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Algorithm: k.Algorithm, Use: "sig"}
+
+	switch public := k.private.Public().(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encoding.EncodeToString(public)
+	case *ecdsa.PublicKey:
+		jwk.KeyType = "EC"
+		jwk.Curve = "P-256"
+		jwk.X = encoding.EncodeToString(pad(public.X.Bytes(), es256Size))
+		jwk.Y = encoding.EncodeToString(pad(public.Y.Bytes(), es256Size))
+	}
+	return jwk
+}
+
+// sign creates a signature of the signing input.
+func (k Key) sign(input []byte) ([]byte, error) {
+	switch private := k.private.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(private, input), nil
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, private, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		return append(pad(r.Bytes(), es256Size), pad(s.Bytes(), es256Size)...), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type: %T", k.private)
+	}
+}
+
+// JWK represents a public key in the JSON web key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWKS represents a set of public keys in the JSON web key set format.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// publicKey decodes the public key of a JSON web key.
+func (j JWK) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case j.KeyType == "OKP" && j.Curve == "Ed25519":
+		x, err := encoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	case j.KeyType == "EC" && j.Curve == "P-256":
+		x, err := encoding.DecodeString(j.X)
+		if err != nil {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		y, err := encoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !public.Curve.IsOnCurve(public.X, public.Y) {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		return public, nil
+	default:
+		return nil, fmt.Errorf("%w: %s %s", errUnsupportedKey, j.KeyType, j.Curve)
+	}
+}
+
+// verify checks a signature of the signing input with a public key.
+func verify(algorithm string, public crypto.PublicKey, input []byte, signature []byte) bool {
+	switch k := public.(type) {
+	case ed25519.PublicKey:
+		return algorithm == EdDSA && ed25519.Verify(k, input, signature)
+	case *ecdsa.PublicKey:
+		if algorithm != ES256 || len(signature) != 2*es256Size {
+			return false
+		}
+		digest := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(signature[:es256Size])
+		s := new(big.Int).SetBytes(signature[es256Size:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+// pad left pads bytes with zeros to the provided size.
+func pad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"fmt"
+	"time"
+	"untitled_game/core/token"
+)
+
+// Token represents a signed access token along with its expiration time.
+type Token struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// SignerConfig represents configuration options for a token signer. Tokens are signed with the
+// active key. All keys are published in the key set so that tokens signed with a previously active
+// key remain verifiable while keys are rotated.
+type SignerConfig struct {
+	Keys        []Key
+	ActiveKeyID string
+	Issuer      string
+	Audience    string
+	TTL         time.Duration
+}
+
+// Signer provides methods for issuing signed access tokens and publishing the public keys that are
+// used to verify them.
+type Signer interface {
+	Issue(claims Claims) (Token, error)
+	JWKS() JWKS
+}
+
+type signer struct {
+	active   Key
+	jwks     JWKS
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+// NewSigner creates a new token signer.
+func NewSigner(cfg SignerConfig) (Signer, error) {
+	s := &signer{
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.TTL,
+	}
+
+	found := false
+	for _, k := range cfg.Keys {
+		if k.ID == cfg.ActiveKeyID {
+			s.active = k
+			found = true
+		}
+		s.jwks.Keys = append(s.jwks.Keys, k.JWK())
+	}
+	if !found {
+		return nil, fmt.Errorf("active signing key not found: %q", cfg.ActiveKeyID)
+	}
+	return s, nil
+}
+
+// Issue signs a new access token with the provided claims. The issuer, audience, issued at,
+// expiration, and token id claims are set by the signer.
+func (s *signer) Issue(claims Claims) (Token, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+
+	id, err := token.Generate(16)
+	if err != nil {
+		return Token{}, err
+	}
+
+	claims.Issuer = s.issuer
+	claims.Audience = s.audience
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = expiresAt.Unix()
+	claims.ID = id
+
+	h, err := encodeSegment(header{Algorithm: s.active.Algorithm, Type: "JWT", KeyID: s.active.ID})
+	if err != nil {
+		return Token{}, err
+	}
+
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		return Token{}, err
+	}
+
+	input := h + "." + payload
+	signature, err := s.active.sign([]byte(input))
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{input + "." + encoding.EncodeToString(signature), expiresAt}, nil
+}
+
+// JWKS returns the public keys of all signing keys.
+func (s *signer) JWKS() JWKS {
+	return s.jwks
+}
@@ -0,0 +1,222 @@
+package jwt
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// leeway is the amount of clock skew that is tolerated when checking the time based claims of a
+// token.
+const leeway = 30 * time.Second
+
+// KeySet provides a method Key for looking up the public key with the given key id.
+type KeySet interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// Verifier provides a method Verify for checking access tokens. Game servers use a verifier to
+// authenticate players without contacting the accounts service for every request.
+type Verifier interface {
+	Verify(token string) (Claims, error)
+}
+
+type verifier struct {
+	keys     KeySet
+	issuer   string
+	audience string
+}
+
+// NewVerifier creates a new token verifier. Tokens must be signed by a key in the key set and carry
+// the provided issuer and audience.
+func NewVerifier(keys KeySet, issuer string, audience string) Verifier {
+	return &verifier{keys, issuer, audience}
+}
+
+// Verify checks the signature and claims of a token and returns the claims if the token is valid.
+func (v *verifier) Verify(t string) (Claims, error) {
+	parts, err := split(t)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, err
+	}
+
+	public, err := v.keys.Key(h.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if !verify(h.Algorithm, public, []byte(parts[0]+"."+parts[1]), signature) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+
+	if claims.Issuer != v.issuer || claims.Audience != v.audience {
+		return Claims{}, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if claims.NotBefore != 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return Claims{}, ErrInvalidToken
+	}
+	if now.Add(-leeway).After(time.Unix(claims.ExpiresAt, 0)) {
+		return Claims{}, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+type staticKeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+// NewStaticKeySet creates a key set from a fixed set of JSON web keys.
+func NewStaticKeySet(jwks JWKS) (KeySet, error) {
+	keys, err := parseJWKS(jwks)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKeySet{keys}, nil
+}
+
+// Key looks up the public key with the given key id.
+func (s *staticKeySet) Key(kid string) (crypto.PublicKey, error) {
+	public, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return public, nil
+}
+
+// RemoteKeySetConfig represents configuration options for a remote key set. The key set is fetched
+// again when a token references an unknown key id, but no more often than the refresh interval.
+type RemoteKeySetConfig struct {
+	URL             string
+	Client          *http.Client
+	RefreshInterval time.Duration
+}
+
+type remoteKeySet struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	lastFetched time.Time
+}
+
+// NewRemoteKeySet creates a key set that is fetched from a JSON web key set url, such as the
+// /.well-known/jwks.json route of the accounts service. Keys are cached, so rotated keys are picked
+// up automatically as soon as tokens signed with them are seen.
+func NewRemoteKeySet(cfg RemoteKeySetConfig) KeySet {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.RefreshInterval == 0 {
+		cfg.RefreshInterval = time.Minute
+	}
+	return &remoteKeySet{
+		url:             cfg.URL,
+		client:          cfg.Client,
+		refreshInterval: cfg.RefreshInterval,
+		keys:            make(map[string]crypto.PublicKey),
+	}
+}
+
+// Key looks up the public key with the given key id, fetching the key set if the key id is not
+// cached. The key set is fetched without holding the lock, so that lookups of cached keys are not
+// blocked by a slow key set url.
+func (s *remoteKeySet) Key(kid string) (crypto.PublicKey, error) {
+	public, fetch := s.cached(kid)
+	if public != nil {
+		return public, nil
+	}
+	if !fetch {
+		return nil, ErrUnknownKey
+	}
+
+	keys, err := s.fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	public, ok := keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return public, nil
+}
+
+// cached looks up the cached public key with the given key id. If the key id is not cached and the
+// refresh interval has passed since the key set was last fetched, then the key set must be fetched,
+// and the fetch time is updated so that concurrent lookups do not fetch it again.
+func (s *remoteKeySet) cached(kid string) (crypto.PublicKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if public, ok := s.keys[kid]; ok {
+		return public, false
+	}
+
+	if time.Since(s.lastFetched) < s.refreshInterval {
+		return nil, false
+	}
+	s.lastFetched = time.Now()
+	return nil, true
+}
+
+// fetch retrieves and parses the key set from the url.
+func (s *remoteKeySet) fetch() (map[string]crypto.PublicKey, error) {
+	res, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch key set: unexpected status: %s", res.Status)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+	return parseJWKS(jwks)
+}
+
+// parseJWKS decodes the public keys of a key set. Keys are indexed by key id. Keys of unsupported
+// types are skipped, so that a key set can publish keys for other consumers.
+func parseJWKS(jwks JWKS) (map[string]crypto.PublicKey, error) {
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		public, err := jwk.publicKey()
+		if err != nil {
+			if errors.Is(err, errUnsupportedKey) {
+				continue
+			}
+			return nil, err
+		}
+		keys[jwk.KeyID] = public
+	}
+	return keys, nil
+}
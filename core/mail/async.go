@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"log"
+	"sync"
+)
+
+// AsyncMailer is a mailer that delivers messages in the background. Close waits for the messages
+// that are still being delivered.
+type AsyncMailer interface {
+	Mailer
+	Close() error
+}
+
+type asyncMailer struct {
+	mailer Mailer
+	log    *log.Logger
+	wg     sync.WaitGroup
+}
+
+// NewAsyncMailer creates a new mailer that delivers messages with the provided mailer without
+// waiting for delivery to finish. This keeps slow or failing delivery from affecting the response to
+// the request that sent the message, and keeps response timing from depending on whether a message
+// was sent. Messages that cannot be delivered are logged.
+func NewAsyncMailer(mailer Mailer, log *log.Logger) AsyncMailer {
+	return &asyncMailer{mailer: mailer, log: log}
+}
+
+// Send delivers the message in the background. Delivery errors are logged instead of returned.
+func (m *asyncMailer) Send(msg Message) error {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := m.mailer.Send(msg); err != nil {
+			m.log.Printf("could not send email %q: %v", msg.Subject, err)
+		}
+	}()
+	return nil
+}
+
+// Close waits for the messages that are still being delivered.
+func (m *asyncMailer) Close() error {
+	m.wg.Wait()
+	return nil
+}
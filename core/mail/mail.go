@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"bytes"
+	"mime"
+	"time"
+)
+
+// Message represents a plain text email message.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer provides a method Send for delivering email messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// format renders a message into an RFC 5322 formatted email that is sent from the given address.
+func format(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + msg.To + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes()
+}
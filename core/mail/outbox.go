@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"untitled_game/core/token"
+)
+
+type outboxMailer struct {
+	dir  string
+	from string
+}
+
+// NewOutboxMailer creates a new mailer that writes messages as .eml files to the provided directory
+// instead of delivering them. This is intended for local development and tests.
+func NewOutboxMailer(dir string, from string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &outboxMailer{dir, from}, nil
+}
+
+// Send writes the message to a new file in the outbox directory.
+func (m *outboxMailer) Send(msg Message) error {
+	suffix, err := token.Generate(8)
+	if err != nil {
+		return err
+	}
+
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + suffix + ".eml"
+	return ioutil.WriteFile(filepath.Join(m.dir, name), format(m.from, msg), 0644)
+}
@@ -0,0 +1,96 @@
+package normalize
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// provider represents the addressing rules of an email provider. Addresses at any of the domains
+// of a provider are delivered to the same mailbox as the same local part at the canonical domain.
+// If dots are ignored, then they can be added anywhere in the local part. If the provider supports
+// subaddressing, then anything after the separator in the local part is ignored.
+type provider struct {
+	domain     string
+	ignoreDots bool
+	separator  string
+}
+
+var (
+	gmail     = provider{domain: "gmail.com", ignoreDots: true, separator: "+"}
+	icloud    = provider{domain: "icloud.com", separator: "+"}
+	plusAlias = provider{separator: "+"}
+)
+
+// providers maps the domains of well known email providers to their addressing rules. Providers
+// that use the domain as it is do not have a canonical domain.
+var providers = map[string]provider{
+	"gmail.com":      gmail,
+	"googlemail.com": gmail,
+	"icloud.com":     icloud,
+	"me.com":         icloud,
+	"mac.com":        icloud,
+	"outlook.com":    plusAlias,
+	"hotmail.com":    plusAlias,
+	"live.com":       plusAlias,
+	"msn.com":        plusAlias,
+	"fastmail.com":   plusAlias,
+	"fastmail.fm":    plusAlias,
+	"protonmail.com": plusAlias,
+	"protonmail.ch":  plusAlias,
+	"proton.me":      plusAlias,
+	"pm.me":          plusAlias,
+}
+
+// Email returns the canonical form of an email address, which is used to decide whether two email
+// addresses belong to the same person. Two addresses with the same canonical form are delivered to
+// the same mailbox, or are made to look the same with characters from other scripts. The canonical
+// form is only used for matching and is not a deliverable address.
+//
+// The local part is brought into unicode normalization form C, full width characters are replaced
+// with their ASCII equivalents, and letters from other scripts that look like latin letters are
+// replaced with those letters. The address is lowercased, and the domain is converted to its ASCII
+// form. Finally, the addressing rules of the email provider are applied, such as ignoring dots and
+// subaddresses.
+func Email(address string) string {
+	address = strings.TrimSpace(address)
+
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return strings.ToLower(strings.Map(foldWidth, norm.NFC.String(address)))
+	}
+
+	local := strings.ToLower(strings.Map(foldConfusable, norm.NFC.String(address[:at])))
+	domain := Domain(address[at+1:])
+
+	p, ok := providers[domain]
+	if !ok {
+		return local + "@" + domain
+	}
+
+	if p.separator != "" {
+		if i := strings.Index(local, p.separator); i > 0 {
+			local = local[:i]
+		}
+	}
+	if p.ignoreDots {
+		local = strings.Replace(local, ".", "", -1)
+	}
+	if p.domain != "" {
+		domain = p.domain
+	}
+	return local + "@" + domain
+}
+
+// Domain returns the canonical form of a domain name. The domain is converted to its ASCII form
+// with the lookup profile of UTS #46, which lowercases it, maps full width characters and
+// ideographic full stops, normalizes it, and punycode encodes labels that contain non-ASCII
+// characters. A domain that is not a valid internationalized domain name is only lowercased.
+func Domain(domain string) string {
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		ascii = strings.ToLower(domain)
+	}
+	return strings.TrimSuffix(ascii, ".")
+}
@@ -0,0 +1,42 @@
+package normalize
+
+// confusables maps letters from the cyrillic and greek scripts to the latin letters that they are
+// visually indistinguishable from. Both cases are listed, since lowercasing an uppercase lookalike
+// does not always produce a lowercase lookalike.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'А': 'a', 'а': 'a', 'В': 'b', 'Е': 'e', 'е': 'e', 'К': 'k', 'М': 'm', 'Н': 'h', 'О': 'o',
+	'о': 'o', 'Р': 'p', 'р': 'p', 'С': 'c', 'с': 'c', 'Т': 't', 'У': 'y', 'у': 'y', 'Х': 'x',
+	'х': 'x', 'І': 'i', 'і': 'i', 'Ј': 'j', 'ј': 'j', 'Ѕ': 's', 'ѕ': 's', 'Һ': 'h', 'һ': 'h',
+	'ԁ': 'd', 'Ԛ': 'q', 'ԛ': 'q', 'Ԝ': 'w', 'ԝ': 'w', 'Ӏ': 'l', 'ӏ': 'l',
+
+	// Greek
+	'Α': 'a', 'Β': 'b', 'Ε': 'e', 'Ζ': 'z', 'Η': 'h', 'Ι': 'i', 'ι': 'i', 'Κ': 'k', 'Μ': 'm',
+	'Ν': 'n', 'ν': 'v', 'Ο': 'o', 'ο': 'o', 'Ρ': 'p', 'ρ': 'p', 'Τ': 't', 'Υ': 'y', 'Χ': 'x',
+
+	// Latin
+	'ı': 'i',
+}
+
+// foldWidth replaces full width forms of ASCII characters and ideographic full stops with their
+// ASCII equivalents.
+func foldWidth(r rune) rune {
+	switch {
+	case r >= '！' && r <= '～':
+		return r - '！' + '!'
+	case r == '。', r == '｡':
+		return '.'
+	default:
+		return r
+	}
+}
+
+// foldConfusable replaces full width characters and letters that look like latin letters with
+// their latin equivalents.
+func foldConfusable(r rune) rune {
+	r = foldWidth(r)
+	if c, ok := confusables[r]; ok {
+		return c
+	}
+	return r
+}
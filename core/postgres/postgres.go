@@ -9,8 +9,9 @@ import (
 )
 
 const (
-	uniqueViolationErrorCode   = "23505"
-	duplicateDatabaseErrorCode = "42P04"
+	uniqueViolationErrorCode     = "23505"
+	foreignKeyViolationErrorCode = "23503"
+	duplicateDatabaseErrorCode   = "42P04"
 )
 
 // Config represents configuration options for a postgres connection pool.
@@ -66,6 +67,12 @@ func IsUniqueViolationError(err error) bool {
 	return ok && pgErr.Code == uniqueViolationErrorCode
 }
 
+// IsForeignKeyViolationError returns true if the error is a postgres foreign key violation error.
+func IsForeignKeyViolationError(err error) bool {
+	pgErr, ok := err.(*pq.Error)
+	return ok && pgErr.Code == foreignKeyViolationErrorCode
+}
+
 // IsDuplicateDatabaseError returns true if the error is a postgres duplicate database error.
 func IsDuplicateDatabaseError(err error) bool {
 	pgErr, ok := err.(*pq.Error)
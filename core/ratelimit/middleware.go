@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"untitled_game/core/api"
+)
+
+// KeyFunc derives the key that a request is rate limited by. If an empty key is returned, then the
+// request is not rate limited.
+type KeyFunc func(r *http.Request) string
+
+// ByIP rate limits requests by the ip address of the client.
+func ByIP(r *http.Request) string {
+	return "ip:" + api.ClientIP(r)
+}
+
+// Middleware rate limits requests to a route. Each route should use a unique name so that routes
+// have separate quotas. The RateLimit-Limit, RateLimit-Remaining, and RateLimit-Reset headers are
+// added to every response. If the quota for the request key has been used up, then the middleware
+// responds to the request with a too many requests error and sets the Retry-After header.
+func Middleware(res api.Responder, limiter Limiter, name string, limit Limit, key KeyFunc) api.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := limiter.Allow(name+":"+k, limit)
+			if err != nil {
+				res.RespondError(w, err)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+			if !result.Allowed {
+				res.RespondError(w, api.ErrTooManyRequests.WithHeader("Retry-After", ceilSeconds(result.RetryAfter)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// ceilSeconds formats a duration as a whole number of seconds, rounding up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
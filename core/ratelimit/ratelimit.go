@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit represents the maximum number of requests that are allowed within a window. Limits are
+// enforced with a token bucket that holds up to Requests tokens and is refilled evenly over the
+// window, so short bursts up to the full limit are allowed.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// rate returns the number of tokens that are added to the bucket per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Result represents the outcome of a rate limited request.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter provides a method Allow for consuming a request from the quota of a key.
+type Limiter interface {
+	Allow(key string, limit Limit) (Result, error)
+	Close() error
+}
+
+// newResult creates a result from the number of tokens left in a bucket after a request.
+func newResult(allowed bool, tokens float64, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Requests) - tokens) / limit.rate()),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+	return res
+}
+
+// seconds converts a number of seconds into a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// bucketsPrefix is used to prefix redis keys that represent token buckets.
+const bucketsPrefix = "ratelimit:"
+
+// cmdAllow refills a token bucket based on the time since it was last updated and consumes a token
+// if one is available. The bucket expires once it would have refilled completely. Whether the
+// request is allowed and the number of remaining tokens are returned. The tokens are returned as a
+// string since redis truncates lua numbers to integers.
+var cmdAllow = redis.NewScript(1, `
+	local capacity = tonumber(ARGV[1])
+	local rate = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+	local tokens = tonumber(state[1]) or capacity
+	local updated = tonumber(state[2]) or now
+	tokens = math.min(capacity, tokens + math.max(0, now - updated) / 1000 * rate)
+	local allowed = 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+		allowed = 1
+	end
+	redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+	redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+	return {allowed, tostring(tokens)}
+`)
+
+// RedisConfig represents configuration options for a redis rate limiter.
+type RedisConfig struct {
+	Redis string
+}
+
+type redisLimiter struct {
+	redis *redis.Pool
+}
+
+// NewRedisLimiter creates a new rate limiter that keeps its state in redis. Limits are shared by
+// every process that uses the same redis instance.
+func NewRedisLimiter(cfg RedisConfig) (Limiter, error) {
+	r := &redis.Pool{
+		IdleTimeout: 3 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(cfg.Redis)
+		},
+	}
+
+	conn := r.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("PING"); err != nil {
+		return nil, err
+	}
+	return &redisLimiter{r}, nil
+}
+
+// Allow consumes a request from the quota of the key.
+func (l *redisLimiter) Allow(key string, limit Limit) (Result, error) {
+	conn := l.redis.Get()
+	defer conn.Close()
+
+	res, err := redis.Values(cmdAllow.Do(conn, bucketsPrefix+key,
+		limit.Requests,
+		strconv.FormatFloat(limit.rate(), 'f', -1, 64),
+		time.Now().UnixNano()/int64(time.Millisecond),
+	))
+	if err != nil {
+		return Result{}, err
+	}
+
+	var allowed int
+	var tokens string
+	if _, err := redis.Scan(res, &allowed, &tokens); err != nil {
+		return Result{}, err
+	}
+
+	remaining, err := strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(allowed == 1, remaining, limit), nil
+}
+
+// Close closes the underlying redis connection.
+func (l *redisLimiter) Close() error {
+	return l.redis.Close()
+}
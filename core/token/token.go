@@ -1,6 +1,10 @@
 package token
 
-import "crypto/rand"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
 
 // chars represents the set of characters that can be included in a generated token.
 const chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
@@ -18,3 +22,10 @@ func Generate(length int) (string, error) {
 	}
 	return string(bytes), nil
 }
+
+// Hash returns the hex encoded SHA-256 digest of a token. Tokens that grant access to an account are
+// stored hashed so that a leaked database row cannot be used directly.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
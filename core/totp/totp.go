@@ -0,0 +1,110 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// period is the number of seconds that each time step lasts.
+	period = 30
+
+	// digits is the number of digits in a generated code.
+	digits = 6
+
+	// modulus is used to reduce a truncated hmac value to a code with the required number of digits
+	// and is equal to 10^digits.
+	modulus = 1000000
+
+	// skew is the number of time steps before and after the current step that are also accepted
+	// when validating a code. This allows for small amounts of clock drift on the client device.
+	skew = 1
+
+	// secretSize is the number of random bytes in a generated secret.
+	secretSize = 20
+)
+
+// encoding is used to encode secrets. Authenticator apps expect unpadded base32 secrets.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret creates a new random base32 encoded secret.
+func GenerateSecret() (string, error) {
+	bytes := make([]byte, secretSize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(bytes), nil
+}
+
+// Step returns the time step that the provided time falls in.
+func Step(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+// Code generates the code for a secret at the provided time step as defined by RFC 6238.
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation as described in RFC 4226 section 5.3.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%modulus), nil
+}
+
+// Validate checks a code against the secret at the provided time. If the code is valid, then the
+// time step that the code was generated for is returned so that callers can reject replayed codes.
+func Validate(secret string, code string, t time.Time) (int64, bool) {
+	if len(code) != digits {
+		return 0, false
+	}
+
+	current := Step(t)
+	for step := current - skew; step <= current+skew; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI creates an otpauth provisioning uri for the secret. Authenticator apps can import the secret
+// by scanning a QR code that encodes the uri.
+func URI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(period))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
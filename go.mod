@@ -11,5 +11,7 @@ require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/lib/pq v1.3.0
 	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
+	golang.org/x/net v0.0.0-20200813134508-3edf25e44fcc
+	golang.org/x/text v0.3.3
 	gopkg.in/yaml.v2 v2.3.0
 )
@@ -0,0 +1,64 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS account_roles (
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('player', 'Granted to every account.'),
+    ('moderator', 'Moderates players and their display names.'),
+    ('support', 'Helps players recover access to their accounts.'),
+    ('admin', 'Full access to all accounts.');
+
+INSERT INTO permissions (name, description) VALUES
+    ('game.play', 'Play the game.'),
+    ('accounts.read', 'View the details of any account.'),
+    ('accounts.ban', 'Ban accounts and lift bans.'),
+    ('accounts.logout', 'Revoke the sessions of any account.'),
+    ('accounts.verify', 'Mark accounts as verified.'),
+    ('accounts.reset_password', 'Send password reset emails for any account.'),
+    ('accounts.unlock', 'Unlock accounts that were locked after failed logins.'),
+    ('display_names.read', 'View the display name history of any account.'),
+    ('roles.manage', 'Assign roles to accounts.');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('player', 'game.play'),
+    ('moderator', 'accounts.read'),
+    ('moderator', 'accounts.ban'),
+    ('moderator', 'accounts.logout'),
+    ('moderator', 'display_names.read'),
+    ('support', 'accounts.read'),
+    ('support', 'accounts.logout'),
+    ('support', 'accounts.verify'),
+    ('support', 'accounts.reset_password'),
+    ('support', 'accounts.unlock'),
+    ('support', 'display_names.read'),
+    ('admin', 'accounts.read'),
+    ('admin', 'accounts.ban'),
+    ('admin', 'accounts.logout'),
+    ('admin', 'accounts.verify'),
+    ('admin', 'accounts.reset_password'),
+    ('admin', 'accounts.unlock'),
+    ('admin', 'display_names.read'),
+    ('admin', 'roles.manage');
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DELETE FROM permissions WHERE name = 'audit.read';
+
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS trigger_audit_events_scrub_only();
+DROP FUNCTION IF EXISTS trigger_append_only();
+
+COMMIT;
@@ -0,0 +1,63 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    actor_id INTEGER,
+    account_id INTEGER,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_account_id ON audit_events (account_id, created_at);
+CREATE INDEX audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX audit_events_email_hash ON audit_events ((payload->>'email_hash')) WHERE payload ? 'email_hash';
+CREATE INDEX audit_events_created_at ON audit_events (created_at);
+
+-- Audit events are append-only so that they can be trusted when investigating incidents.
+CREATE OR REPLACE FUNCTION trigger_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The only change that is allowed is scrubbing the client details and payload of an event when the
+-- account that it belongs to is purged. What happened and when is kept, but not who it identifies.
+CREATE OR REPLACE FUNCTION trigger_audit_events_scrub_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.id = OLD.id AND NEW.type = OLD.type AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+        AND NEW.account_id IS NOT DISTINCT FROM OLD.account_id AND NEW.created_at = OLD.created_at
+        AND NEW.ip = '' AND NEW.user_agent = '' AND NEW.payload = '{}' THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_scrub_only
+BEFORE UPDATE ON audit_events
+FOR EACH ROW
+EXECUTE PROCEDURE trigger_audit_events_scrub_only();
+
+CREATE TRIGGER audit_events_append_only
+BEFORE DELETE ON audit_events
+FOR EACH ROW
+EXECUTE PROCEDURE trigger_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+BEFORE TRUNCATE ON audit_events
+FOR EACH STATEMENT
+EXECUTE PROCEDURE trigger_append_only();
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit.read', 'View the security audit log.');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('support', 'audit.read'),
+    ('admin', 'audit.read');
+
+COMMIT;
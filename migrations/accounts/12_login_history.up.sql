@@ -0,0 +1,23 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS login_history (
+    id BIGSERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    session_key TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL,
+    network TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    device TEXT NOT NULL,
+    device_fingerprint TEXT NOT NULL,
+    new_device BOOLEAN NOT NULL DEFAULT false,
+    revoke_token_hash TEXT UNIQUE NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX login_history_account_id ON login_history (account_id, created_at);
+CREATE INDEX login_history_device_fingerprint ON login_history (account_id, device_fingerprint);
+CREATE INDEX login_history_network ON login_history (account_id, network);
+CREATE INDEX login_history_created_at ON login_history (created_at);
+
+COMMIT;
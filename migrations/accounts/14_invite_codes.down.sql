@@ -0,0 +1,8 @@
+BEGIN;
+
+DELETE FROM permissions WHERE name = 'invites.manage';
+
+DROP TABLE IF EXISTS invite_redemptions;
+DROP TABLE IF EXISTS invite_codes;
+
+COMMIT;
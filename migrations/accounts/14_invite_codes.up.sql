@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS invite_codes (
+    code TEXT PRIMARY KEY,
+    max_uses INTEGER NOT NULL CHECK (max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0 CHECK (uses <= max_uses),
+    note TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES accounts (id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX invite_codes_created_at ON invite_codes (created_at);
+
+CREATE TABLE IF NOT EXISTS invite_redemptions (
+    code TEXT NOT NULL REFERENCES invite_codes (code) ON DELETE CASCADE,
+    account_id INTEGER UNIQUE NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (code, account_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('invites.manage', 'Mint and revoke invite codes.');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'invites.manage');
+
+COMMIT;
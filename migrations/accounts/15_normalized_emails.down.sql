@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS accounts_normalized_email;
+ALTER TABLE accounts DROP COLUMN IF EXISTS normalized_email;
+
+COMMIT;
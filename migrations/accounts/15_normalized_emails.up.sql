@@ -0,0 +1,46 @@
+BEGIN;
+
+ALTER TABLE accounts ADD COLUMN normalized_email TEXT;
+
+-- normalize_email mirrors the ASCII rules of the normalize package so that existing accounts can be
+-- backfilled. New and changed email addresses are normalized by the application. Addresses with
+-- non-ASCII characters are renormalized with the full rules by running the server once with
+-- --normalize-emails.
+CREATE FUNCTION normalize_email(address TEXT) RETURNS TEXT AS $$
+DECLARE
+    local TEXT := lower(substring(btrim(address) from '^(.*)@[^@]*$'));
+    domain TEXT := rtrim(lower(substring(btrim(address) from '@([^@]*)$')), '.');
+BEGIN
+    IF local IS NULL THEN
+        RETURN lower(btrim(address));
+    END IF;
+
+    IF domain IN ('gmail.com', 'googlemail.com', 'icloud.com', 'me.com', 'mac.com', 'outlook.com', 'hotmail.com', 'live.com', 'msn.com',
+        'fastmail.com', 'fastmail.fm', 'protonmail.com', 'protonmail.ch', 'proton.me', 'pm.me') AND position('+' in local) > 1 THEN
+        local := left(local, position('+' in local) - 1);
+    END IF;
+
+    IF domain IN ('gmail.com', 'googlemail.com') THEN
+        RETURN replace(local, '.', '') || '@gmail.com';
+    ELSIF domain IN ('icloud.com', 'me.com', 'mac.com') THEN
+        RETURN local || '@icloud.com';
+    END IF;
+    RETURN local || '@' || domain;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Accounts that were registered with different forms of the same email address before emails were
+-- normalized keep their email address, but only the oldest account is given the normalized email.
+UPDATE accounts SET normalized_email = normalized.email
+FROM (
+    SELECT id, normalize_email(email) AS email, row_number() OVER (PARTITION BY normalize_email(email) ORDER BY created_at, id) AS rank
+    FROM accounts
+    WHERE email IS NOT NULL
+) AS normalized
+WHERE accounts.id = normalized.id AND normalized.rank = 1;
+
+DROP FUNCTION normalize_email(TEXT);
+
+CREATE UNIQUE INDEX accounts_normalized_email ON accounts (normalized_email);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS accounts_verification_token;
+
+COMMIT;
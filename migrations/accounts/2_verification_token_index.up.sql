@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX accounts_verification_token ON accounts (verification_token) WHERE verification_token IS NOT NULL;
+
+COMMIT;
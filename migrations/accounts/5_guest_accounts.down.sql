@@ -0,0 +1,11 @@
+BEGIN;
+
+DELETE FROM accounts WHERE email IS NULL;
+
+DROP INDEX IF EXISTS accounts_guest_last_login_at;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_guest_credentials;
+ALTER TABLE accounts DROP COLUMN IF EXISTS guest_device_hash;
+ALTER TABLE accounts ALTER COLUMN password SET NOT NULL;
+ALTER TABLE accounts ALTER COLUMN email SET NOT NULL;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE accounts ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE accounts ALTER COLUMN password DROP NOT NULL;
+ALTER TABLE accounts ADD COLUMN guest_device_hash TEXT UNIQUE;
+ALTER TABLE accounts ADD CONSTRAINT accounts_guest_credentials CHECK ((email IS NULL) = (password IS NULL));
+
+CREATE INDEX accounts_guest_last_login_at ON accounts (COALESCE(last_login_at, created_at)) WHERE email IS NULL;
+
+COMMIT;
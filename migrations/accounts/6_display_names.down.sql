@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS display_name_history;
+
+DROP INDEX IF EXISTS accounts_display_name;
+ALTER TABLE accounts DROP COLUMN IF EXISTS display_name_changed_at;
+ALTER TABLE accounts DROP COLUMN IF EXISTS display_name;
+
+COMMIT;
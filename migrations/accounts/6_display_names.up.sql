@@ -0,0 +1,18 @@
+BEGIN;
+
+ALTER TABLE accounts ADD COLUMN display_name TEXT;
+ALTER TABLE accounts ADD COLUMN display_name_changed_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX accounts_display_name ON accounts (lower(display_name));
+
+CREATE TABLE IF NOT EXISTS display_name_history (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    display_name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX display_name_history_account_id ON display_name_history (account_id, created_at);
+CREATE INDEX display_name_history_display_name ON display_name_history (lower(display_name));
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS accounts_deletion_scheduled_at;
+ALTER TABLE accounts DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE accounts DROP COLUMN IF EXISTS deletion_scheduled_at;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE accounts ADD COLUMN deletion_scheduled_at TIMESTAMPTZ;
+ALTER TABLE accounts ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX accounts_deletion_scheduled_at ON accounts (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+COMMIT;
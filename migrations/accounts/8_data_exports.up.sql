@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id TEXT PRIMARY KEY,
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    format TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    data BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    claimed_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX data_exports_account_id ON data_exports (account_id);
+CREATE INDEX data_exports_pending ON data_exports (created_at) WHERE status = 'pending';
+CREATE INDEX data_exports_running ON data_exports (claimed_at) WHERE status = 'running';
+CREATE UNIQUE INDEX data_exports_in_progress ON data_exports (account_id) WHERE status IN ('pending', 'running');
+
+COMMIT;